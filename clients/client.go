@@ -14,16 +14,18 @@
 
 package client
 
+import "context"
+
 // Client Generic client type
 type Client interface {
-	Get() error
-	Create() error
-	Update() error
-	Delete() error
+	Get(ctx context.Context) error
+	Create(ctx context.Context) error
+	Update(ctx context.Context) error
+	Delete(ctx context.Context) error
 }
 
 // AppClient client interface allowing to interact with a service or an application
 type AppClient interface {
-	Execute(cmd string, params []string) error
-	Ping() error
+	Execute(ctx context.Context, cmd string, params []string) error
+	Ping(ctx context.Context) error
 }
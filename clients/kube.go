@@ -16,9 +16,14 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 // Kube client to address Kube resources
@@ -31,24 +36,98 @@ type Kube struct {
 // Blank assignement to check type
 var _ Client = &Kube{}
 
-// Get creates a resource
-func (c *Kube) Get() error {
-	return c.Client.Get(context.TODO(), types.NamespacedName{Namespace: c.Object.GetNamespace(), Name: c.Object.GetName()}, c.Object)
+// Get gets a resource
+func (c *Kube) Get(ctx context.Context) error {
+	return c.Client.Get(ctx, types.NamespacedName{Namespace: c.Object.GetNamespace(), Name: c.Object.GetName()}, c.Object)
 }
 
 // Create creates a resource
-func (c *Kube) Create() error {
-	return c.Client.Create(context.TODO(), c.Object)
+func (c *Kube) Create(ctx context.Context) error {
+	return c.Client.Create(ctx, c.Object)
 }
 
 // Update update a resource
-func (c *Kube) Update() error {
-	return c.Client.Update(context.TODO(), c.Object)
+func (c *Kube) Update(ctx context.Context) error {
+	return c.Client.Update(ctx, c.Object)
 }
 
 // Delete delete a resource
-func (c *Kube) Delete() error {
-	return c.Client.Delete(context.TODO(), c.Object)
+func (c *Kube) Delete(ctx context.Context) error {
+	return c.Client.Delete(ctx, c.Object)
+}
+
+// DeleteWithPropagation delete a resource with the propagation policy provided (Orphan, Background or Foreground).
+// An empty policy stands for the default policy of the resource on the Cluster.
+func (c *Kube) DeleteWithPropagation(ctx context.Context, policy metav1.DeletionPropagation) error {
+	if policy == "" {
+		return c.Delete(ctx)
+	}
+	return c.Client.Delete(ctx, c.Object, client.PropagationPolicy(policy))
+}
+
+// PatchAnnotations patches only the annotations provided of a resource, with a JSON merge patch. Its other fields are left unchanged.
+// The object is updated with the patched result returned by the Cluster.
+func (c *Kube) PatchAnnotations(ctx context.Context, annotations map[string]string) error {
+	data, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, c.Object, client.RawPatch(types.MergePatchType, data))
+}
+
+// ApplyOptions are the options of a server-side apply request
+type ApplyOptions struct {
+	// FieldManager is the name of the manager owning the applied fields
+	FieldManager string
+	// ForceConflicts forces the ownership of the fields already owned by another manager, instead of failing on conflict
+	ForceConflicts bool
+}
+
+// Apply creates or updates a resource with the server-side apply method. Only the fields set in the object are owned by the field manager.
+// The object is updated with the applied result returned by the Cluster.
+func (c *Kube) Apply(ctx context.Context, opts ApplyOptions) error {
+	obj, err := applyConfiguration(c.Object, c.Client.Scheme())
+	if err != nil {
+		return err
+	}
+
+	patchOpts := []client.PatchOption{client.FieldOwner(opts.FieldManager)}
+	if opts.ForceConflicts {
+		patchOpts = append(patchOpts, client.ForceOwnership)
+	}
+	if err := c.Client.Patch(ctx, obj, client.Apply, patchOpts...); err != nil {
+		return err
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), c.Object)
+}
+
+// applyConfiguration Build the unstructured apply configuration of an object, i.e. without the fields managed by the Cluster (status, managedFields, resourceVersion,...)
+// The GVK is determined thanks to the scheme, the one of the object is used as fallback.
+func applyConfiguration(obj client.Object, scheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if scheme != nil {
+		if schemeGVK, err := apiutil.GVKForObject(obj, scheme); err == nil {
+			gvk = schemeGVK
+		}
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	delete(u.Object, "status")
+	u.SetManagedFields(nil)
+	u.SetResourceVersion("")
+	u.SetUID("")
+	u.SetGeneration(0)
+	u.SetSelfLink("")
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+
+	return u, nil
 }
 
 // NewKube New client mapper
@@ -0,0 +1,59 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestApplyConfiguration(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "core/v1", Kind: "ConfigMap"}, // Wrong group, fixed by the scheme
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              "name",
+			ResourceVersion:   "123",
+			UID:               "uid",
+			CreationTimestamp: metav1.Now(),
+			ManagedFields:     []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+			Labels:            map[string]string{"a": "b"},
+		},
+		Data: map[string]string{"key": "value"},
+	}
+
+	u, err := applyConfiguration(cm, clientgoscheme.Scheme)
+	require.NoError(t, err)
+	require.Equal(t, "v1", u.GetAPIVersion())
+	require.Equal(t, "ConfigMap", u.GetKind())
+	require.Equal(t, "", u.GetResourceVersion())
+	require.Equal(t, "", string(u.GetUID()))
+	require.Nil(t, u.GetManagedFields())
+	_, exists, _ := unstructured.NestedFieldNoCopy(u.Object, "metadata", "creationTimestamp")
+	require.False(t, exists)
+	require.Equal(t, map[string]string{"a": "b"}, u.GetLabels())
+	value, _, _ := unstructured.NestedString(u.Object, "data", "key")
+	require.Equal(t, "value", value)
+
+	// Without any scheme, the object GVK is kept
+	u, err = applyConfiguration(cm, nil)
+	require.NoError(t, err)
+	require.Equal(t, "core/v1", u.GetAPIVersion())
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+// Package metrics exposes the results of the OKT reconcilers as Prometheus metrics, registered on the controller-runtime
+// registry served by the manager (see sigs.k8s.io/controller-runtime/pkg/metrics).
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// Labels of the metrics
+const (
+	ControllerLabel = "controller"
+	OperationLabel  = "operation"
+	KindLabel       = "kind"
+	ResultLabel     = "result"
+	StateLabel      = "state"
+)
+
+// Values of the ResultLabel label
+const (
+	ResultSuccess = "success"
+	ResultRequeue = "requeue"
+	ResultError   = "error"
+	ResultGiveUp  = "giveup"
+)
+
+const namespace = "okt"
+
+var (
+	// OperationsTotal counts the operations reported in the results, per operation type and resource kind
+	OperationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Total number of operations reported in the reconciliation results, per operation type and resource kind",
+	}, []string{ControllerLabel, OperationLabel, KindLabel})
+
+	// OperationErrorsTotal counts the operations reported with an error in the results, per operation type and resource kind
+	OperationErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operation_errors_total",
+		Help:      "Total number of operations reported with an error in the reconciliation results, per operation type and resource kind",
+	}, []string{ControllerLabel, OperationLabel, KindLabel})
+
+	// ReconcileDuration is the duration of the reconciliations, per consolidated result
+	ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of the reconciliations in seconds, per consolidated result (success, requeue, error, giveup)",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{ControllerLabel, ResultLabel})
+
+	// StateDuration is the duration of the states of the Stepper engine
+	StateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stepper_state_duration_seconds",
+		Help:      "Duration of the reconciliation states of the Stepper engine in seconds",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{ControllerLabel, StateLabel})
+
+	// RequeueDelay is the consolidated requeue delay of the last reconciliation, per consolidated result (0 if not requeued after a delay)
+	RequeueDelay = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "requeue_delay_seconds",
+		Help:      "Consolidated requeue delay of the last reconciliation in seconds, per consolidated result (success, requeue, error, giveup)",
+	}, []string{ControllerLabel, ResultLabel})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(OperationsTotal, OperationErrorsTotal, ReconcileDuration, StateDuration, RequeueDelay)
+}
+
+// ObserveOperations Count the operations of a reconciliation's results. The kind of an operation is the one of its resource, if any.
+func ObserveOperations(controller string, ops []okterr.OpInfo) {
+	for _, op := range ops {
+		kind := ""
+		if op.Resource != nil {
+			kind = strings.SplitN(op.Resource.KindName(), "/", 2)[0]
+		}
+		OperationsTotal.WithLabelValues(controller, string(op.Operation), kind).Inc()
+		if op.Err != nil {
+			OperationErrorsTotal.WithLabelValues(controller, string(op.Operation), kind).Inc()
+		}
+	}
+}
+
+// ObserveReconcile Observe the duration of a reconciliation and its requeue delay, regarding its consolidated result
+func ObserveReconcile(controller string, duration time.Duration, giveup bool, result reconcile.Result, err error) {
+	res := ResultOf(giveup, result, err)
+	ReconcileDuration.WithLabelValues(controller, res).Observe(duration.Seconds())
+	RequeueDelay.WithLabelValues(controller, res).Set(result.RequeueAfter.Seconds())
+}
+
+// ObserveState Observe the duration of a state of the Stepper engine
+func ObserveState(controller, state string, duration time.Duration) {
+	StateDuration.WithLabelValues(controller, state).Observe(duration.Seconds())
+}
+
+// ResultOf Return the value of the result label for a consolidated result
+func ResultOf(giveup bool, result reconcile.Result, err error) string {
+	switch {
+	case giveup:
+		return ResultGiveUp
+	case err != nil:
+		return ResultError
+	case result.Requeue || result.RequeueAfter > 0:
+		return ResultRequeue
+	}
+	return ResultSuccess
+}
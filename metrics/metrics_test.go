@@ -0,0 +1,72 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+type resourceStub struct{ kindName string }
+
+func (r *resourceStub) Index() string    { return r.kindName }
+func (r *resourceStub) KindName() string { return r.kindName }
+
+func TestObserveOperations(t *testing.T) {
+	const controller = "test-operations"
+
+	results := okterr.NewResultList()
+	results.AddOpSuccess(&resourceStub{"Secret/s1"}, okterr.OperationResultCreated)
+	results.AddOpSuccess(&resourceStub{"Secret/s2"}, okterr.OperationResultCreated)
+	results.AddOp(&resourceStub{"Service/svc"}, okterr.OperationResultCRUDError, errors.New("boom"), 3)
+	results.AddOp(nil, okterr.OperationResultReconcileTimeout, errors.New("timeout"), 0)
+
+	ObserveOperations(controller, results.OpList())
+
+	require.Equal(t, 2.0, testutil.ToFloat64(OperationsTotal.WithLabelValues(controller, string(okterr.OperationResultCreated), "Secret")))
+	require.Equal(t, 0.0, testutil.ToFloat64(OperationErrorsTotal.WithLabelValues(controller, string(okterr.OperationResultCreated), "Secret")))
+	require.Equal(t, 1.0, testutil.ToFloat64(OperationErrorsTotal.WithLabelValues(controller, string(okterr.OperationResultCRUDError), "Service")))
+	require.Equal(t, 1.0, testutil.ToFloat64(OperationErrorsTotal.WithLabelValues(controller, string(okterr.OperationResultReconcileTimeout), "")))
+}
+
+func TestObserveReconcile(t *testing.T) {
+	const controller = "test-reconcile"
+
+	ObserveReconcile(controller, time.Second, false, reconcile.Result{Requeue: true, RequeueAfter: 3 * time.Second}, nil)
+	require.Equal(t, 3.0, testutil.ToFloat64(RequeueDelay.WithLabelValues(controller, ResultRequeue)))
+	require.Equal(t, 1, testutil.CollectAndCount(ReconcileDuration.MustCurryWith(map[string]string{ControllerLabel: controller})))
+
+	ObserveReconcile(controller, time.Second, false, reconcile.Result{RequeueAfter: 5 * time.Second}, nil)
+	require.Equal(t, 5.0, testutil.ToFloat64(RequeueDelay.WithLabelValues(controller, ResultRequeue)), "The gauge reports the last reconciliation")
+
+	ObserveReconcile(controller, time.Second, false, reconcile.Result{}, nil)
+	require.Equal(t, 0.0, testutil.ToFloat64(RequeueDelay.WithLabelValues(controller, ResultSuccess)))
+	require.Equal(t, 5.0, testutil.ToFloat64(RequeueDelay.WithLabelValues(controller, ResultRequeue)), "Per consolidated result")
+}
+
+func TestResultOf(t *testing.T) {
+	err := errors.New("boom")
+	require.Equal(t, ResultSuccess, ResultOf(false, reconcile.Result{}, nil))
+	require.Equal(t, ResultRequeue, ResultOf(false, reconcile.Result{RequeueAfter: time.Second}, nil))
+	require.Equal(t, ResultError, ResultOf(false, reconcile.Result{}, err))
+	require.Equal(t, ResultGiveUp, ResultOf(true, reconcile.Result{}, nil))
+}
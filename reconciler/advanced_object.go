@@ -14,19 +14,36 @@
 package reconciler
 
 import (
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	oktclients "github.com/Orange-OpenSource/Operators-Karma-Tools/clients"
 	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
 	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+	okttools "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/k8sapi"
 )
 
 // AdvancedObject Implementation of the Advanced Reconciler Object based on the Reconciler
 // This reconciler deals with mutable resources.
 type AdvancedObject struct {
 	BasicObject
+
+	// Skip the mutations depending only on the CR spec while it is unchanged (see EnableSpecFastPath())
+	specFastPath bool
 }
 
 // Blank assignement to check type
 var _ Advanced = &AdvancedObject{}
 
+// WithResults Return a copy of this reconciler whose helpers (Create(), Mutate(), Update(),...) report their operations in the results
+// provided, see BasicObject.WithResults()
+func (ar *AdvancedObject) WithResults(results okterr.Results) *AdvancedObject {
+	c := *ar
+	c.Results = results
+	return &c
+}
+
 /*
 // SetParams Defines which params to use to initialize registered resources
 func (r *AdvancedObject) SetParams(params map[string]string) {
@@ -34,19 +51,73 @@ func (r *AdvancedObject) SetParams(params map[string]string) {
 }
 */
 
+// EnableSpecFastPath Skip the mutation of the resources whose mutations depend only on the CR spec (see oktres.SpecAwareResource and
+// MutableResourceObject.SpecOnlyMutation) while the spec is unchanged (see SpecChanged()), if their peer has not drifted (see oktres.DriftDetector).
+// Such a resource is then not updated, its skipped mutation is reported as OperationResultMutationSkipped.
+func (ar *AdvancedObject) EnableSpecFastPath() {
+	ar.specFastPath = true
+}
+
+// canSkipMutation Tells if the mutation of the resource can be skipped, on the fast path: the CR spec is unchanged, the mutations of the resource
+// only depend on it and its peer exists and has not drifted.
+func (ar *AdvancedObject) canSkipMutation(entry oktres.MutableResourceType) (bool, error) {
+	if !ar.specFastPath || entry.IsCreation() || ar.SpecChanged() {
+		return false, nil
+	}
+	specAware, ok := entry.(oktres.SpecAwareResource)
+	if !ok || !specAware.IsSpecOnlyMutation() {
+		return false, nil
+	}
+	detector, ok := entry.(oktres.DriftDetector)
+	if !ok {
+		return false, nil
+	}
+	drifted, err := detector.DetectDrift(entry.GetHashableRef())
+	return !drifted, err
+}
+
 // Mutate Mutates a mutable resource by applying defaults and values brougth by the Custom Resource (CR) of the Operator
 // After potential mutation or modification, compute new object's fingerprint (Hash) and
 // compare it with the existing fingerprint (hash code).
 // Store the new fingerprint in the expected object.
 // The entry's synch status property (NeedResync) is updated as well.
+// With the spec fast path, the mutation can be skipped (see EnableSpecFastPath()).
 // This function adds operation's result in Results list
 // Returns the error if any.
 func (ar *AdvancedObject) Mutate(entry oktres.MutableResourceType) error {
+	skip, err := ar.canSkipMutation(entry)
+	if err != nil {
+		return ar.AddGiveupError(entry, okterr.OperationResultImplementationConcern, err)
+	}
+	if skip {
+		ar.AddOpSuccess(entry, okterr.OperationResultMutationSkipped)
+		return nil
+	}
+
 	if !entry.IsCreation() {
 		hashableRef := entry.GetHashableRef()
-		if err := entry.UpdateSyncStatus(hashableRef); err != nil {
+		if differ, ok := entry.(oktres.Differ); ok {
+			if err := differ.SnapshotPeer(hashableRef); err != nil {
+				return ar.AddGiveupError(entry, okterr.OperationResultImplementationConcern, err)
+			}
+		}
+		if detector, ok := entry.(oktres.DriftDetector); ok {
+			// The drift policy decides whether a modification of the peer has to be reverted
+			if err := ar.detectDrift(entry, detector, hashableRef); err != nil {
+				return ar.AddGiveupError(entry, okterr.OperationResultImplementationConcern, err)
+			}
+		} else if err := entry.UpdateSyncStatus(hashableRef); err != nil {
+			return ar.AddGiveupError(entry, okterr.OperationResultImplementationConcern, err)
+		}
+	}
+
+	// In server-side apply mode, the mutations start from a blank object, as for a creation
+	applied := false
+	if ssaRes, ok := entry.(oktres.ServerSideApplyResource); ok && ssaRes.IsServerSideApplyEnabled() {
+		if err := ssaRes.PrepareApply(); err != nil {
 			return ar.AddGiveupError(entry, okterr.OperationResultImplementationConcern, err)
 		}
+		applied = true
 	}
 
 	if err := entry.PreMutate(ar.GetScheme()); err != nil {
@@ -55,8 +126,8 @@ func (ar *AdvancedObject) Mutate(entry oktres.MutableResourceType) error {
 
 	// Decides if yes or no the Intial Data have to be re-applied to the Expected object (in memory)
 	// Checks first if the Peer object has been modified against its preceeding version on cluster
-	// However, in case of Creation, initial data are mandatory
-	if entry.NeedResync() || entry.IsCreation() {
+	// However, in case of Creation or of server-side apply, initial data are mandatory
+	if entry.NeedResync() || entry.IsCreation() || applied {
 		if err := entry.MutateWithInitialData(); err != nil {
 			return ar.AddGiveupError(entry, okterr.OperationResultImplementationConcern, err)
 		}
@@ -93,6 +164,22 @@ func (ar *AdvancedObject) Mutate(entry oktres.MutableResourceType) error {
 	return nil
 }
 
+// detectDrift Detect an out-of-band modification of the peer and report it.
+// Unless the drift policy is to revert it, the drift does not trigger an update by itself. With the DriftPolicyTolerate policy,
+// the drift is accepted and thus no longer reported once stored (see Update()).
+func (ar *AdvancedObject) detectDrift(entry oktres.MutableResourceType, detector oktres.DriftDetector, hashableRef okthash.HashableRef) error {
+	drifted, err := detector.DetectDrift(hashableRef)
+	if err != nil || !drifted {
+		return err
+	}
+
+	ar.AddOpSuccess(entry, okterr.OperationResultDriftDetected)
+	if detector.GetDriftPolicy() != oktres.DriftPolicyTolerate {
+		return nil
+	}
+	return detector.AcceptDrift(hashableRef)
+}
+
 // MutateAllResources Mutate all Mutable resources
 // Return immediatley if a raised or current consolidated error is GiveUpReconciliation
 // If stopOnError is true, stop as soon as an error is raised
@@ -120,24 +207,46 @@ func (ar *AdvancedObject) MutateAllResources(stopOnError bool) error {
 
 // Update update a mutable resource (so, having a Mutator interface) and modified against its Cluster Peer (NeerResync() = true).
 // The resource passed as arguement must be a Mutable resource
+// The fingerprint of the updated peer, or of a drift accepted or a legacy peer without update, is stored on the peer (see oktres.DriftDetector).
 // This function adds operation's result in reconciler's Results list
 // Returns the error if any.
 func (ar *AdvancedObject) Update(resource oktres.MutableResourceType) error {
 	// Is a mutable and unsynched resource with its cluster version ? => Do Uppdate
 	if resource.NeedResync() {
-		if err := resource.UpdatePeer(); err != nil {
+		// The changes are computed before the update which refreshes the object with its new peer version
+		var changes []string
+		if differ, ok := resource.(oktres.Differ); ok {
+			changes, _ = differ.DiffFromPeer(resource.GetHashableRef()) // Only informative, not an issue for the update
+		}
+
+		if err := resource.UpdatePeer(ar.GetContext()); err != nil {
 			return ar.AddOp(resource, okterr.OperationResultCRUDError, err, requeueDurationOnCRUDError)
 		}
+		if err := ar.recordPeer(resource); err != nil {
+			return err
+		}
 
-		ar.AddOpSuccess(resource, okterr.OperationResultUpdated)
+		ar.AddOpSuccessWithDetails(resource, okterr.OperationResultUpdated, changes)
 		return nil
 	}
 
+	if detector, ok := resource.(oktres.DriftDetector); ok {
+		if detector.IsLegacyPeer() {
+			// Peer written by a former version, recorded as is
+			if err := ar.recordPeer(resource); err != nil {
+				return err
+			}
+		} else if err := detector.SaveAcceptedDrift(ar.GetContext()); err != nil {
+			return ar.AddOp(resource, okterr.OperationResultCRUDError, err, requeueDurationOnCRUDError)
+		}
+	}
+
 	ar.AddOpSuccess(resource, okterr.OperationResultNone)
 	return nil
 }
 
 // CreateOrUpdateAllResources Utility method to Create or Update all OKT resources (taking care of their types and created/mutation status)
+// Resources are processed in their dependency order (see GetSortedResources()).
 // The parameter maxCreation specified the maximum count of resources to create in one shot
 // Return immediatley if a raised or current consolidated error is GiveUpReconciliation
 // If stopOnError is true, stop as soon as an error is raised
@@ -147,9 +256,12 @@ func (ar *AdvancedObject) CreateOrUpdateAllResources(maxCreation uint16, stopOnE
 		return err
 	}
 
-	var err error
+	entries, err := ar.sortedResources()
+	if err != nil {
+		return err
+	}
 
-	for _, res := range ar.GetRegisteredResources() {
+	for _, res := range entries {
 		if res.IsCreation() {
 			if err = ar.Create(res, maxCreation); err != nil {
 				if stopOnError {
@@ -170,3 +282,97 @@ func (ar *AdvancedObject) CreateOrUpdateAllResources(maxCreation uint16, stopOnE
 
 	return err
 }
+
+// prune Delete a resource previously managed by this reconciler but no longer registered.
+// Return true if the entry has to remain in the inventory (dry-run or error) and the error if any.
+func (ar *AdvancedObject) prune(entry okttools.InventoryEntry, dryRun bool) (keep bool, err error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(entry.GroupVersionKind())
+	obj.SetNamespace(entry.Namespace)
+	obj.SetName(entry.Name)
+	peer := oktclients.NewKube(ar.Client, obj)
+
+	if err = peer.Get(ar.GetContext()); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil // Already gone
+		}
+		return true, ar.AddOp(entry, okterr.OperationResultResourceUnreadable, err, requeueDurationOnResourceUnreadable)
+	}
+
+	if okttools.HasKeepAnnotation(obj) {
+		ar.AddOpSuccess(entry, okterr.OperationResultPruneKept)
+		return false, nil
+	}
+
+	if dryRun {
+		ar.AddOpSuccess(entry, okterr.OperationResultPruneDryRun)
+		return true, nil
+	}
+
+	if err = peer.Delete(ar.GetContext()); err != nil && !k8serrors.IsNotFound(err) {
+		return true, ar.AddOp(entry, okterr.OperationResultCRUDError, err, requeueDurationOnCRUDError)
+	}
+	ar.AddOpSuccess(entry, okterr.OperationResultDeleted)
+
+	return false, nil
+}
+
+// PruneUnregisteredResources Delete resources managed by a previous reconciliation but not registered during the current one.
+// The managed resources are recorded in an inventory stored as an annotation of the CR (see k8sapi.OKTInventoryAnnotationName) which is updated here.
+// A resource annotated with k8sapi.OKTKeepAnnotationName set to "true" is never deleted but removed from the inventory.
+// In dryRun mode, resources to prune are only reported in Results (OperationResultPruneDryRun) and remain in the inventory.
+// Return immediatley if any error has been raised during the reconciliation, as the registry can then be incomplete.
+// If stopOnError is true, stop as soon as an error is raised
+// Return the last raised error during the deletions
+func (ar *AdvancedObject) PruneUnregisteredResources(dryRun, stopOnError bool) error {
+	if _, err := ar.ConsolidatedError(); err != nil {
+		return err
+	}
+
+	cr := ar.GetCR()
+	previous, err := okttools.GetInventory(cr)
+	if err != nil {
+		return ar.AddGiveupError(&crInfo{cr: cr}, okterr.OperationResultImplementationConcern, err)
+	}
+
+	current := make(okttools.Inventory)
+	for _, res := range ar.GetRegisteredResources() {
+		if keyed, ok := res.(oktres.KeyedResource); ok {
+			key := keyed.GetNGVK()
+			current.Add(okttools.NewInventoryEntry(key.GVK(), key.NamespacedName()))
+		}
+	}
+
+	orphans := previous.Difference(current)
+	for i, entry := range orphans {
+		keep, errPrune := ar.prune(entry, dryRun)
+		if keep {
+			current.Add(entry)
+		}
+		if errPrune == nil {
+			continue
+		}
+		err = errPrune
+		if stopOnError {
+			// Not yet treated orphans remain in the inventory
+			for _, remaining := range orphans[i+1:] {
+				current.Add(remaining)
+			}
+			break
+		}
+	}
+
+	if previous.Equal(current) {
+		return err
+	}
+
+	if errInv := okttools.SetInventory(cr, current); errInv != nil {
+		return ar.AddGiveupError(&crInfo{cr: cr}, okterr.OperationResultImplementationConcern, errInv)
+	}
+	if errInv := ar.Client.Update(ar.GetContext(), cr); errInv != nil {
+		return ar.AddOp(&crInfo{cr: cr}, okterr.OperationResultCRUDError, errInv, requeueDurationOnCRUDError)
+	}
+	ar.snapshotCR()
+
+	return err
+}
@@ -17,6 +17,9 @@ package reconciler
 import (
 	"context"
 	"fmt"
+	"math"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -25,9 +28,12 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	oktclients "github.com/Orange-OpenSource/Operators-Karma-Tools/clients"
+	oktmetrics "github.com/Orange-OpenSource/Operators-Karma-Tools/metrics"
 	oktregistry "github.com/Orange-OpenSource/Operators-Karma-Tools/registry"
 	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
 	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
@@ -98,42 +104,144 @@ type BasicObject struct {
 	okterr.Results
 	cr                      client.Object
 	managedStatusConditions *[]v1.Condition
+	statusConditionsIndex   []int         // Index of the status conditions field in the CR struct, nil if they are not a field of the CR
+	declaredConditions      []string      // Condition types declared in addition to the standard ones
+	crSnapshot              client.Object // Copy of the CR as fetched, to patch its status, nil if the CR can not be copied
+	conditionsSet           []string      // Condition types set since the CR was fetched
+	observedGeneration      *int64        // Observed generation in the status of the CR as fetched, nil if unknown
 	env                     string
 	controllerName          string
 
+	// Finalizer added to the CR (empty if disabled) and finalization tasks, shared by the requests
+	finalizerName string
+	finalizers    []finalizerTask
+
 	registry *oktregistry.Registry
 
 	engine      Engine
 	crIsFetched bool
 
+	// Context of the request being served, with the reconciliation timeout if any
+	ctx              context.Context
+	reconcileTimeout time.Duration
+
 	// Indicates wether or not te CR has to be finalized
 	CRHasToBeFinalized bool
 
+	// Server-side apply options applied to the registered resources, nil when this mode is disabled
+	serverSideApply *oktclients.ApplyOptions
+
+	// Records operations as K8S events, nil when disabled
+	events *eventsRecorder
+
+	// Name of the controller used as label of the Prometheus metrics, empty when the metrics are disabled
+	metricsController string
+
+	// Policy computing the requeue delays (default policy if nil) and count of consecutive requeues per CR, shared by the requests
+	requeuePolicy   okterr.RequeuePolicy
+	requeueAttempts *okterr.RequeueAttempts
+
 	Params map[string]string
+
+	// Serve each request with a copy of the reconciler (see EnableConcurrentRequests())
+	concurrentRequests bool
+
+	lock *sync.Mutex // Shared by the requests copied from this reconciler
 }
 
 // blank assignment to correct implementation
-//var _ context.Context = &BasicObject{}
+// var _ context.Context = &BasicObject{}
 var _ Basic = &BasicObject{}
 
 // Reconcile is the native Reconcile method  (sigs.k8s.io) called by the Operator manager
 // This is the interface between OKT Reconciler and the OperatorSDK
+// The requests are served one by one, unless the concurrent requests are enabled (see EnableConcurrentRequests()): each request is
+// then served by a copy of the reconciler with its own CR, registry, results and engine (see ConcurrentEngine).
+// At the end, the reconciler keeps the CR and the results of the last request served, for inspection purpose only (i.e. tests).
 func (r *BasicObject) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	start := time.Now()
+
+	if !r.concurrentRequests {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		result, err := r.reconcile(ctx, request)
+		r.requeueAttempts.Done(request.NamespacedName.String(), isRequeued(result, err))
+		r.observe(r.Results, start, result, err)
+		return result, err
+	}
+
+	req, err := r.newRequest()
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("the request can not be served concurrently: %w", err)
+	}
+
+	result, err := req.reconcile(ctx, request)
+	r.requeueAttempts.Done(request.NamespacedName.String(), isRequeued(result, err))
+	r.observe(req.Results, start, result, err)
+	r.done(req)
+
+	return result, err
+}
+
+// isRequeued Tells if a new reconciliation is requested
+func isRequeued(result reconcile.Result, err error) bool {
+	return err != nil || result.Requeue || result.RequeueAfter > 0
+}
+
+// observe Report the results of a reconciliation as metrics, if enabled
+func (r *BasicObject) observe(results okterr.Results, start time.Time, result reconcile.Result, err error) {
+	if r.metricsController == "" {
+		return
+	}
+	giveup, _ := results.ConsolidatedError()
+	oktmetrics.ObserveOperations(r.metricsController, results.OpList())
+	oktmetrics.ObserveReconcile(r.metricsController, time.Since(start), giveup, result, err)
+}
+
+// reconcile Serves a reconciliation request
+// The context provided to the clients and engine carries the reconciliation timeout, if any, and the logger with the request values.
+func (r *BasicObject) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	r.ResetAllResults() // Reset results and stats to empty
+	r.UseRequeuePolicy(r.requeuePolicy, r.requeueAttempts.Current(request.NamespacedName.String()))
 	r.registry = oktregistry.New()
 
+	if r.reconcileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.reconcileTimeout)
+		defer cancel()
+	}
+	r.ctx = logr.NewContext(ctx, r.Log.WithValues("request", request.NamespacedName.String()))
+	defer func() { r.ctx = nil }()
+	r.engine.SetContext(r.ctx)
+	if engine, measurable := r.engine.(MeasurableEngine); measurable && r.metricsController != "" {
+		engine.EnableMetrics(r.metricsController)
+	}
+
 	r.Log.V(1).Info("Reconcile: " + request.NamespacedName.String())
 
 	// Fetch, from Cluster, the Operator's Custom Resource instance
 	if err := r.FetchCR(request.NamespacedName); err != nil {
 		return r.ConsolidatedSigsK8S()
 	}
+	if err := r.ensureCRFinalizer(); err != nil {
+		return r.ConsolidatedSigsK8S()
+	}
 
 	// Now, launch the Reconcile process !
 	r.engine.Run()
 
+	// The engine may not report the timeout on its own
+	if err := r.ctx.Err(); err != nil && r.OpsCount(okterr.OperationResultReconcileTimeout) == 0 {
+		r.AddOp(nil, okterr.OperationResultReconcileTimeout, err, 0)
+	}
+
 	if r.CRHasToBeFinalized {
-		r.RemoveCRFinalizer()
+		r.finalizeCR()
+	}
+
+	if r.events != nil {
+		r.events.record(r.cr, r.OpList())
 	}
 
 	r.DisplayOpList(r.Log)
@@ -149,6 +257,9 @@ const (
 	requeueDurationOnResultNone         uint16 = 3
 	requeueDurationOnCreateDelayed      uint16 = 4
 	requeueDurationOnStatusUpdateError  uint16 = 5
+	requeueDurationOnDeleteInProgress   uint16 = 2
+	requeueDurationOnDependencyNotReady uint16 = 5
+	requeueDurationOnNotReady           uint16 = 5
 )
 
 type crInfo struct {
@@ -176,10 +287,16 @@ func (r *BasicObject) Init(env string, cr client.Object, statusConditions *[]v1.
 	r.Log = r.Log.WithName("ENV=" + env)
 	r.env = env
 	r.Results = okterr.NewResultList()
+	r.registry = oktregistry.New()
+	r.lock = &sync.Mutex{}
+	r.requeueAttempts = okterr.NewRequeueAttempts()
 	r.cr = cr
 
 	if statusConditions != nil {
 		r.managedStatusConditions = statusConditions
+		if cr != nil && reflect.ValueOf(cr).Elem().Kind() == reflect.Struct {
+			r.statusConditionsIndex, _ = fieldIndex(reflect.ValueOf(cr).Elem(), reflect.ValueOf(statusConditions).Pointer(), statusConditionsType)
+		}
 		r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultStatusEnabled)
 	}
 
@@ -191,20 +308,55 @@ func (r *BasicObject) Init(env string, cr client.Object, statusConditions *[]v1.
 	return nil
 }
 
-const (
-	// statusConditionType stands for a Reconciliation status
-	statusConditionType = "ReconciliationSuccess"
-)
-
 // GetManagedStatusConditionType Return the status condition type managed by the Reconciler
 // If no condition list has been provided during the Init() call for this reconciler, return an empty string ("").
 func (r *BasicObject) GetManagedStatusConditionType() string {
 	if r.managedStatusConditions != nil {
-		return statusConditionType
+		return ReconcileSuccessConditionType
 	}
 	return ""
 }
 
+// EnableServerSideApply Enable the server-side apply mode for all the resources registered by this reconciler, except
+// for the ones which already enabled this mode on their own. Resources are then created and updated as the field manager provided.
+// If fieldManager is empty, the name "okt-<env>" is used, where env is the environment provided at Init() time.
+// With forceConflicts, the fields already owned by another manager are taken over instead of failing on conflict.
+func (r *BasicObject) EnableServerSideApply(fieldManager string, forceConflicts bool) {
+	if fieldManager == "" {
+		fieldManager = "okt-" + r.env
+	}
+	r.serverSideApply = &oktclients.ApplyOptions{FieldManager: fieldManager, ForceConflicts: forceConflicts}
+}
+
+// EnableEvents Record the operations selected by the options as Kubernetes events on the CR, and optionally on the resources concerned.
+// A failed operation is recorded as a Warning event, else as a Normal one. The same event is not recorded twice during a dedup window,
+// thus an error raised at each reconciliation cycle does not flood the event stream.
+// The recorder is typically provided by the manager: mgr.GetEventRecorderFor("my-controller")
+func (r *BasicObject) EnableEvents(recorder record.EventRecorder, opts EventsOptions) {
+	r.events = newEventsRecorder(recorder, opts)
+}
+
+// EnableMetrics Report the results of the reconciliations as Prometheus metrics (see the okt metrics package), with the controller name
+// provided as label: count of operations per type and resource kind, duration of the reconciliations and of the engine's states, requeue delay.
+// The metrics are registered on the controller-runtime registry, thus served by the manager.
+func (r *BasicObject) EnableMetrics(controller string) {
+	r.metricsController = controller
+}
+
+// SetRequeuePolicy Set the policy computing the requeue delay of the operations requesting a requeue (on error or after a delay),
+// regarding the count of consecutive requeued reconciliations of the CR (see okterr.FixedRequeuePolicy, ExponentialRequeuePolicy,
+// TableRequeuePolicy). With a policy set, a reconciliation on error is requeued after the delay computed without returning the error
+// to the manager, else the manager would ignore the delay. The default policy keeps the delays requested, up to 6 hours.
+func (r *BasicObject) SetRequeuePolicy(policy okterr.RequeuePolicy) {
+	r.requeuePolicy = policy
+}
+
+// SetReconcileTimeout Set a maximum duration for each reconciliation. When it is exceeded, the clients requests are cancelled and the
+// reconciliation is aborted with the OperationResultReconcileTimeout error (and thus requeued). No timeout if set to 0 (default).
+func (r *BasicObject) SetReconcileTimeout(timeout time.Duration) {
+	r.reconcileTimeout = timeout
+}
+
 // SetEngine Set the OKT engine to use with this reconciler
 func (r *BasicObject) SetEngine(engine Engine) {
 	r.engine = engine
@@ -212,6 +364,23 @@ func (r *BasicObject) SetEngine(engine Engine) {
 	engine.SetResults(r.Results)
 }
 
+// GetContext return the context of the request being served, context.TODO() if none
+func (r *BasicObject) GetContext() context.Context {
+	if r.ctx == nil {
+		return context.TODO()
+	}
+	return r.ctx
+}
+
+// WithResults Return a copy of this reconciler whose helpers (Create(), Delete(),...) report their operations in the results provided
+// instead of the reconciler's ones, i.e. in the results of a region run in parallel with others (see engines.RegionRun).
+// The copy shares the CR, the registry and the settings of this reconciler: do not register resources nor update the CR status with it.
+func (r *BasicObject) WithResults(results okterr.Results) *BasicObject {
+	c := *r
+	c.Results = results
+	return &c
+}
+
 // GetCR return Custom Resource
 func (r *BasicObject) GetCR() client.Object {
 	return r.cr
@@ -245,7 +414,7 @@ func (r *BasicObject) GetName() string {
 // FetchCR Fetch Custom (primary) Resource from the K8S Cluster using the Client
 // provided by the Operator's Manager for this Reconciler
 func (r *BasicObject) FetchCR(namespacedName types.NamespacedName) error {
-	err := r.Client.Get(context.TODO(), namespacedName, r.cr)
+	err := r.Client.Get(r.GetContext(), namespacedName, r.cr)
 
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -258,8 +427,15 @@ func (r *BasicObject) FetchCR(namespacedName types.NamespacedName) error {
 		return r.Results.AddOp(nil, okterr.OperationResultCRUnreadable, err, requeueDurationOnResourceUnreadable)
 	}
 	r.crIsFetched = true
+	r.snapshotCR()
+	r.conditionsSet = nil
+	r.observedGeneration = nil
+	if observed := r.statusObservedGeneration(); observed != nil {
+		generation := *observed
+		r.observedGeneration = &generation
+	}
 	r.controllerName = r.cr.GetName() //TODO? + "-" + r.env
-	r.CRHasToBeFinalized = okttools.IsBeingDeleted(r.cr) && okttools.HasFinalizer(r.cr, r.controllerName)
+	r.CRHasToBeFinalized = okttools.IsBeingDeleted(r.cr) && r.hasCRFinalizer()
 
 	if r.CRHasToBeFinalized {
 		r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultCRIsFinalizing)
@@ -269,24 +445,45 @@ func (r *BasicObject) FetchCR(namespacedName types.NamespacedName) error {
 	return nil
 }
 
-// RemoveCRFinalizer Remove CR finalizer (based on Controller Name) and update CR on Cluster.
+// SpecChanged Tells if the CR spec changed since the last successful reconciliation, i.e. if the generation of the CR differs from the
+// observed generation in its status (recorded by ManageSuccess()). Always true if the status has no ObservedGeneration field (next to its
+// conditions if they are managed), if the CR has no generation or before the CR is fetched.
+func (r *BasicObject) SpecChanged() bool {
+	if !r.crIsFetched || r.observedGeneration == nil || r.cr.GetGeneration() == 0 {
+		return true
+	}
+	return r.cr.GetGeneration() != *r.observedGeneration
+}
+
+// RemoveCRFinalizer Remove CR finalizer (see SetFinalizerName(), and the one named after the CR by the former versions) and update CR on Cluster.
 // You must Ensure first that the CR has a finalizer to remove!
 func (r *BasicObject) RemoveCRFinalizer() error {
+	okttools.RemoveFinalizer(r.cr, r.finalizerName)
 	okttools.RemoveFinalizer(r.cr, r.controllerName)
 
-	if err := r.Client.Update(context.TODO(), r.cr); err != nil {
-		r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultCRUDError, err, 0)
+	if err := r.Client.Update(r.GetContext(), r.cr); err != nil {
+		r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultCRFinalizationUpdateError, err, requeueDurationOnCRUDError)
 		return err
 	}
 
+	r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultCRFinalized)
 	return nil
 }
 
+// isFinalizationCompleted Tells if the CR finalizer can be removed, i.e. no error raised and no resource deletion still in progress
+func (r *BasicObject) isFinalizationCompleted() bool {
+	if _, err := r.Results.ConsolidatedError(); err != nil {
+		return false
+	}
+	return r.Results.OpsCount(okterr.OperationResultDeleteInProgress) == 0
+}
+
 // RegisterResource Register OKT Resource in Reconciler registry.
 // Sync OKT Resource with Peer and check its modification status.
 // The modification status is obtained thanks to a hash key computed on the objects' spec.
 // Any modification is thus detected because a new computed key will produce a new hash
 // different than the one stored in the annotations.
+// A resource not initialized or already registered (same index) gives up the reconciliation with OperationResultRegistrationAborted.
 // TODO: Later let the possibility to specify the client to use for each resource (as parameter ?) and
 // not use systematicaly the client provided by the Manager ?
 func (r *BasicObject) RegisterResource(resource oktres.Resource) error {
@@ -297,17 +494,29 @@ func (r *BasicObject) RegisterResource(resource oktres.Resource) error {
 
 	// Put in reconciler registry
 	if err = r.registry.AddEntry(resource); err != nil {
-		return r.Results.AddGiveupError(resource, okterr.OperationResultImplementationConcern, err)
+		return r.Results.AddGiveupError(resource, okterr.OperationResultRegistrationAborted, err)
 	}
 
 	// Get Peer object if it exists and then concludes it is a creation of a new resource or not
-	if err := resource.SyncFromPeer(); err != nil {
+	if err := resource.SyncFromPeer(r.GetContext()); err != nil {
 		return r.Results.AddOp(resource, okterr.OperationResultResourceUnreadable, err, requeueDurationOnResourceUnreadable)
 	}
 
 	// Propagate params, if any, to this resource
 	resource.SetData(r.Params)
 
+	// Tell whether the CR spec changed
+	if specAware, ok := resource.(oktres.SpecAwareResource); ok {
+		specAware.SetSpecChanged(r.SpecChanged())
+	}
+
+	// Propagate the server-side apply mode, if enabled at the reconciler level
+	if r.serverSideApply != nil {
+		if ssaRes, ok := resource.(oktres.ServerSideApplyResource); ok && !ssaRes.IsServerSideApplyEnabled() {
+			ssaRes.EnableServerSideApply(r.serverSideApply.FieldManager, r.serverSideApply.ForceConflicts)
+		}
+	}
+
 	r.Results.AddOpSuccess(resource, okterr.OperationResultRegistrationSuccess)
 	return nil
 }
@@ -317,15 +526,63 @@ func (r *BasicObject) GetResource(index string) oktres.Resource {
 	return r.registry.GetEntry(index)
 }
 
+// GetRegistry Return a read only access to the registry, to look up the registered resources by kind, name, labels,...
+func (r *BasicObject) GetRegistry() oktregistry.Reader {
+	return r.registry
+}
+
 // GetRegisteredResources Return a slice on all entry pointers in the registry
 func (r *BasicObject) GetRegisteredResources() []oktres.Resource {
 	return r.registry.Entries()
 }
 
+// GetSortedResources Return the registered resources in their dependency order (see oktres.DependentResource):
+// a resource comes after the resources it depends on, else the registration order is kept.
+// Return an error if the dependencies are unregistered or form a cycle.
+func (r *BasicObject) GetSortedResources() ([]oktres.Resource, error) {
+	return r.registry.SortedEntries()
+}
+
+// sortedResources Return the registered resources in their dependency order, or give up on an error in the dependencies
+func (r *BasicObject) sortedResources() ([]oktres.Resource, error) {
+	entries, err := r.registry.SortedEntries()
+	if err != nil {
+		return nil, r.AddGiveupError(nil, okterr.OperationResultImplementationConcern, err)
+	}
+	return entries, nil
+}
+
+// dependenciesReady Tell if all the resources the given one depends on exist on the Cluster and are ready (see oktres.Readiness).
+// If not, the reason is returned.
+func (r *BasicObject) dependenciesReady(resource oktres.Resource) (bool, string, error) {
+	dependent, ok := resource.(oktres.DependentResource)
+	if !ok {
+		return true, "", nil
+	}
+
+	for _, index := range dependent.Dependencies() {
+		dep := r.registry.GetEntry(index)
+		if dep == nil {
+			return false, "", fmt.Errorf("%s depends on an unregistered resource: %s", resource.KindName(), index)
+		}
+		if dep.IsCreation() {
+			return false, dep.KindName() + " not created", nil
+		}
+		if readiness, ok := dep.(oktres.Readiness); ok {
+			if ready, reason := readiness.IsReady(); !ready {
+				return false, dep.KindName() + " not ready: " + reason, nil
+			}
+		}
+	}
+	return true, "", nil
+}
+
 // Create creates the given K8S resource on Kubernetes Cluster
 // Warning!! Before calling this function you must ensure (with res.IsCreation() test) that the resource need to be created and does not yet exist (else it will raise an error)
 // MaxCreation is a limit you want to set to avoid to create too much resources during one reconciliation phase. If set to 0, there's NO limit.
 // In case where the max creation count were reached, the create request is not called and a new reconciliation request dealyed at later time (set by requeueDurationOnCreateDelayed seconds)
+// A resource depending on other resources (see oktres.DependentResource) is created only once they exist and are ready. Else the creation is
+// reported as OperationResultDependencyNotReady with a new reconciliation request delayed (by requeueDurationOnDependencyNotReady seconds).
 // This function adds operation's result in reconciler's Results list
 // Returns the error if any.
 func (r *BasicObject) Create(resource oktres.Resource, maxCreation uint16) error {
@@ -336,14 +593,46 @@ func (r *BasicObject) Create(resource oktres.Resource, maxCreation uint16) error
 		}
 	}
 
-	if err := resource.CreatePeer(); err != nil {
+	ready, reason, err := r.dependenciesReady(resource)
+	if err != nil {
+		return r.AddGiveupError(resource, okterr.OperationResultImplementationConcern, err)
+	}
+	if !ready {
+		r.Log.V(1).Info("Creation waiting for dependencies", "resource", resource.KindName(), "reason", reason)
+		r.AddOp(resource, okterr.OperationResultDependencyNotReady, nil, requeueDurationOnDependencyNotReady)
+		return nil
+	}
+
+	if err := resource.CreatePeer(r.GetContext()); err != nil {
 		return r.AddOp(resource, okterr.OperationResultCRUDError, err, requeueDurationOnCRUDError)
 	}
+	if err := r.recordPeer(resource); err != nil {
+		return err
+	}
 	r.AddOpSuccess(resource, okterr.OperationResultCreated)
 	return nil
 }
 
+// recordPeer Store the fingerprint of a mutable resource peer right after its creation or update, if the resource is a drift detector.
+// Thus the fields defaulted by the Cluster are not detected as a drift.
+func (r *BasicObject) recordPeer(resource oktres.Resource) error {
+	mutable, ok := resource.(oktres.MutableResourceType)
+	if !ok {
+		return nil
+	}
+	detector, ok := resource.(oktres.DriftDetector)
+	if !ok {
+		return nil
+	}
+
+	if err := detector.RecordPeer(r.GetContext(), mutable.GetHashableRef()); err != nil {
+		return r.AddOp(resource, okterr.OperationResultCRUDError, err, requeueDurationOnCRUDError)
+	}
+	return nil
+}
+
 // CreateAllResources is a convenient method to Create all OKT resources (taking care of their created status)
+// Resources are created in their dependency order (see GetSortedResources()).
 // The parameter maxCreation specified the maximum count of resources to create in one shot
 // Return immediatley if a raised or current consolidated error is GiveUpReconciliation
 // If stopOnError is true, stop as soon as an error is raised
@@ -353,9 +642,12 @@ func (r *BasicObject) CreateAllResources(maxCreation uint16, stopOnError bool) e
 		return err
 	}
 
-	var err error
+	entries, err := r.sortedResources()
+	if err != nil {
+		return err
+	}
 
-	for _, res := range r.GetRegisteredResources() {
+	for _, res := range entries {
 		if res.IsCreation() {
 			if err = r.Create(res, maxCreation); err != nil {
 				if stopOnError {
@@ -368,35 +660,95 @@ func (r *BasicObject) CreateAllResources(maxCreation uint16, stopOnError bool) e
 	return err
 }
 
+// Delete deletes the given resource on Kubernetes Cluster with the propagation policy provided (empty for the default policy).
+// A resource not existing on the Cluster (res.IsCreation() is true) is ignored.
+// If waitForGone is true, the existence of the resource is checked again after the deletion request and, while it exists,
+// the operation is reported as OperationResultDeleteInProgress with a new reconciliation request delayed (by requeueDurationOnDeleteInProgress seconds).
+// During the CR finalization, such a pending deletion prevents the CR finalizer to be removed.
+// This function adds operation's result in reconciler's Results list
+// Returns the error if any.
+func (r *BasicObject) Delete(resource oktres.Resource, propagation v1.DeletionPropagation, waitForGone bool) error {
+	if resource.IsCreation() {
+		return nil
+	}
+
+	if err := resource.DeletePeer(r.GetContext(), propagation); err != nil {
+		return r.AddOp(resource, okterr.OperationResultCRUDError, err, requeueDurationOnCRUDError)
+	}
+
+	if waitForGone {
+		gone, err := resource.IsPeerGone(r.GetContext())
+		if err != nil {
+			return r.AddOp(resource, okterr.OperationResultResourceUnreadable, err, requeueDurationOnResourceUnreadable)
+		}
+		if !gone {
+			r.AddOp(resource, okterr.OperationResultDeleteInProgress, nil, requeueDurationOnDeleteInProgress)
+			return nil
+		}
+	}
+
+	r.AddOpSuccess(resource, okterr.OperationResultDeleted)
+	return nil
+}
+
+// DeleteAllResources is a convenient method to Delete all registered OKT resources existing on the Cluster, typically during the CR finalization
+// for resources not owned by the CR (in another namespace for example).
+// Resources are deleted in the reverse order of their dependencies (see GetSortedResources()), thus of their registration without dependencies.
+// See Delete() for the propagation and waitForGone parameters.
+// Return immediatley if a raised or current consolidated error is GiveUpReconciliation
+// If stopOnError is true, stop as soon as an error is raised
+// Return the last raised error during the deletions
+func (r *BasicObject) DeleteAllResources(propagation v1.DeletionPropagation, waitForGone, stopOnError bool) error {
+	if giveup, err := r.ConsolidatedError(); giveup {
+		return err
+	}
+
+	entries, err := r.sortedResources()
+	if err != nil {
+		return err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if errDel := r.Delete(entries[i], propagation, waitForGone); errDel != nil {
+			err = errDel
+			if stopOnError {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
 // ManageSuccess Take care of the Status data of the CR for this reconciler and update it if possible
-// The Status type must fulfill the interface OKT Status (okt/results/Status)
+// The status conditions are set as follow (see StandardConditionTypes): ReconciliationSuccess is True, Degraded is False and Progressing
+// tells if resources have been created or updated, or are not yet ready. Ready is set by WaitReady().
+// The generation of the CR is recorded as observed generation in the status (ObservedGeneration field next to the conditions, if any),
+// see SpecChanged(). The CR status is then patched with its changes.
+// If the status conditions are not managed, only the observed generation is recorded and the status is patched only if it changed.
 func (r *BasicObject) ManageSuccess() {
 	if r.managedStatusConditions == nil {
+		if r.recordObservedGeneration() {
+			r.updateStatus()
+		}
 		return
 	}
 
 	opsCountType, opsCount := r.Results.TotalOpsCount()
 	msg := fmt.Sprint(opsCount, " successful operation(s) of ", opsCountType, "  different type(s)")
-	s := v1.Condition{}
-	s.Type = statusConditionType
-	s.Status = v1.ConditionTrue
-	s.Reason = "Success"
-	s.Message = msg
-	//s.LastTransitionTime = v1.Now()
-
-	k8scond.SetStatusCondition(r.managedStatusConditions, s)
+	r.setCondition(v1.Condition{Type: ReconcileSuccessConditionType, Status: v1.ConditionTrue, Reason: "Success", Message: msg})
+	r.setCondition(v1.Condition{Type: DegradedConditionType, Status: v1.ConditionFalse, Reason: "Success"})
+	r.setProgressingCondition()
+	r.recordObservedGeneration()
 
-	if err := r.Client.Status().Update(context.Background(), r.cr); err != nil {
-		// Do not track this error but requeue
-		r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultStatusUpdateError, nil, requeueDurationOnStatusUpdateError)
-		return
-	}
-	r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultStatusUpdated)
+	r.updateStatus()
 }
 
 // ManageError Take care of the Status data conditions of the CR for this reconciler and update it if possible
-// The condition Type managed here is "Reconciliation" with a Status set to True in case of Success or False in case of Error
-// In case of recurrent error, the timer interval growth up exponentialy at each reconciliation cycle (except for an Update Status problem).
+// The condition Type managed here is "ReconciliationSuccess" with a Status set to True in case of Success or False in case of Error,
+// and the Degraded condition is set to True with the error.
+// In case of recurrent error, the timer interval growth up exponentialy at each reconciliation cycle (except for an Update Status problem):
+// the reconciliation is requeued after the time elapsed since the error first occurred.
 func (r *BasicObject) ManageError() {
 	if r.managedStatusConditions == nil {
 		return
@@ -405,7 +757,7 @@ func (r *BasicObject) ManageError() {
 	giveup, err := r.Results.ConsolidatedError()
 
 	s := v1.Condition{}
-	s.Type = statusConditionType
+	s.Type = ReconcileSuccessConditionType
 	s.Status = v1.ConditionFalse
 	s.Reason = "Error"
 	if giveup {
@@ -414,7 +766,6 @@ func (r *BasicObject) ManageError() {
 	s.Message = err.Error()
 
 	var timeInterval uint16
-	timeInterval = 0
 	sameError := false
 
 	// Is the last Error Condition Equal i.e same Type, same Status AND same Reason than the current error ?
@@ -422,24 +773,31 @@ func (r *BasicObject) ManageError() {
 		prevS := k8scond.FindStatusCondition(*r.managedStatusConditions, s.Type)
 		if prevS.Reason == s.Reason && prevS.Message == s.Message {
 			sameError = true
-			timeInterval = uint16(s.LastTransitionTime.Sub(v1.Now().Time).Round(time.Second).Seconds())
+			timeInterval = sameErrorInterval(time.Since(prevS.LastTransitionTime.Time))
 			s.LastTransitionTime = prevS.LastTransitionTime
 		}
 
 	}
-	k8scond.SetStatusCondition(r.managedStatusConditions, s)
+	r.setCondition(s)
+	r.setCondition(v1.Condition{Type: DegradedConditionType, Status: v1.ConditionTrue, Reason: s.Reason, Message: s.Message})
 
 	if sameError {
 		r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultSameStatusError, err, timeInterval)
 	}
 
-	if err := r.Client.Status().Update(context.Background(), r.cr); err != nil {
-		// Do not track this error but requeue
-		r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultStatusUpdateError, nil, requeueDurationOnStatusUpdateError)
-		return
-	}
+	r.updateStatus()
+}
 
-	r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultStatusUpdated)
+// sameErrorInterval Return the requeue delay, in seconds, after a same error persisting for the duration provided (at least 1 second)
+func sameErrorInterval(persisting time.Duration) uint16 {
+	seconds := persisting.Round(time.Second).Seconds()
+	switch {
+	case seconds < 1:
+		return 1
+	case seconds > math.MaxUint16:
+		return math.MaxUint16
+	}
+	return uint16(seconds)
 }
 
 /*
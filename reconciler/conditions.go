@@ -0,0 +1,272 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8scond "k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Standard status condition types managed by the reconciler (see ManageSuccess() and ManageError())
+const (
+	// ReadyConditionType reports the readiness of the registered resources (see WaitReady())
+	ReadyConditionType = "Ready"
+	// ProgressingConditionType reports that resources are created, updated or not yet ready
+	ProgressingConditionType = "Progressing"
+	// DegradedConditionType reports that the last reconciliation failed
+	DegradedConditionType = "Degraded"
+	// ReconcileSuccessConditionType reports the success or the error of the last reconciliation.
+	// Its value is still "ReconciliationSuccess", the condition type managed by the former versions, for compatibility with the
+	// existing CRs and with the clients waiting for this condition.
+	ReconcileSuccessConditionType = "ReconciliationSuccess"
+)
+
+// StandardConditionTypes are the condition types managed by the reconciler
+var StandardConditionTypes = []string{ReadyConditionType, ProgressingConditionType, DegradedConditionType, ReconcileSuccessConditionType}
+
+// Operations reporting that resources are progressing
+var progressingOperations = []okterr.OperationResult{
+	okterr.OperationResultNotReady,
+	okterr.OperationResultDependencyNotReady,
+	okterr.OperationResultCreateDelayed,
+	okterr.OperationResultDeleteInProgress,
+}
+
+// DeclareConditionTypes Declare your own condition types, in addition to the standard ones (see StandardConditionTypes).
+// They are added to the CR status with an Unknown status until you set them with SetCondition().
+func (r *BasicObject) DeclareConditionTypes(types ...string) {
+	r.declaredConditions = append(r.declaredConditions, types...)
+}
+
+// isConditionType Tells if the condition type is a standard or a declared one
+func (r *BasicObject) isConditionType(condType string) bool {
+	for _, types := range [][]string{StandardConditionTypes, r.declaredConditions} {
+		for _, t := range types {
+			if t == condType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetCondition Set a condition of the CR status, with the generation of the CR as observed generation.
+// The condition is written with the status at the end of the reconciliation (see ManageSuccess()).
+// Return an error if the status conditions are not managed (see Init()) or if the condition type is neither a standard nor a declared one.
+func (r *BasicObject) SetCondition(condType string, status v1.ConditionStatus, reason, message string) error {
+	if r.managedStatusConditions == nil {
+		return errors.New("the status conditions are not managed")
+	}
+	if !r.isConditionType(condType) {
+		return fmt.Errorf("condition type %q not declared", condType)
+	}
+	r.setCondition(v1.Condition{Type: condType, Status: status, Reason: reason, Message: message})
+	return nil
+}
+
+// GetCondition Return a condition of the CR status, nil if not found or if the status conditions are not managed
+func (r *BasicObject) GetCondition(condType string) *v1.Condition {
+	if r.managedStatusConditions == nil {
+		return nil
+	}
+	return k8scond.FindStatusCondition(*r.managedStatusConditions, condType)
+}
+
+// setCondition Set a condition with the generation of the CR. The last transition time is kept if the status is unchanged.
+func (r *BasicObject) setCondition(cond v1.Condition) {
+	if r.cr != nil {
+		cond.ObservedGeneration = r.cr.GetGeneration()
+	}
+	k8scond.SetStatusCondition(r.managedStatusConditions, cond)
+	for _, condType := range r.conditionsSet {
+		if condType == cond.Type {
+			return
+		}
+	}
+	r.conditionsSet = append(r.conditionsSet, cond.Type)
+}
+
+// setProgressingCondition Set the Progressing condition regarding the operations done on the resources
+func (r *BasicObject) setProgressingCondition() {
+	cond := v1.Condition{Type: ProgressingConditionType, Status: v1.ConditionFalse, Reason: "Stable"}
+
+	var waiting uint16
+	for _, op := range progressingOperations {
+		waiting += r.Results.OpsCount(op)
+	}
+	changed := r.Results.OpsCount(okterr.OperationResultCreated) + r.Results.OpsCount(okterr.OperationResultUpdated)
+
+	switch {
+	case waiting > 0:
+		cond.Status, cond.Reason = v1.ConditionTrue, "ResourcesNotReady"
+		cond.Message = fmt.Sprint(waiting, " resource operation(s) in progress")
+	case changed > 0:
+		cond.Status, cond.Reason = v1.ConditionTrue, "ResourcesChanged"
+		cond.Message = fmt.Sprint(changed, " resource(s) created or updated")
+	}
+	r.setCondition(cond)
+}
+
+// statusValue Return the status struct of a CR: the struct holding the status conditions if they are managed, else the Status field of
+// the CR. The value is not valid if none.
+func (r *BasicObject) statusValue(cr client.Object) reflect.Value {
+	if cr == nil || reflect.ValueOf(cr).Kind() != reflect.Ptr || reflect.ValueOf(cr).Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	var status reflect.Value
+	if len(r.statusConditionsIndex) > 0 {
+		status = reflect.ValueOf(cr).Elem().FieldByIndex(r.statusConditionsIndex[:len(r.statusConditionsIndex)-1])
+	} else {
+		status = reflect.ValueOf(cr).Elem().FieldByName("Status")
+	}
+	if !status.IsValid() || status.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	return status
+}
+
+// statusObservedGeneration Return the ObservedGeneration field (int64) of the CR status (see statusValue()), nil if none.
+func (r *BasicObject) statusObservedGeneration() *int64 {
+	status := r.statusValue(r.cr)
+	if !status.IsValid() {
+		return nil
+	}
+
+	field := status.FieldByName("ObservedGeneration")
+	if !field.IsValid() || !field.CanSet() || field.Kind() != reflect.Int64 {
+		return nil
+	}
+	return field.Addr().Interface().(*int64)
+}
+
+// recordObservedGeneration Record the generation of the CR as observed generation in its status (see statusObservedGeneration()).
+// Return true if it changed.
+func (r *BasicObject) recordObservedGeneration() bool {
+	observedGeneration := r.statusObservedGeneration()
+	if observedGeneration == nil || *observedGeneration == r.cr.GetGeneration() {
+		return false
+	}
+	*observedGeneration = r.cr.GetGeneration()
+	return true
+}
+
+// updateStatus Write the status of the CR: the declared conditions not yet set are added as Unknown.
+// The status is patched with the changes made since the CR was fetched, thus the changes made by others meanwhile are not overwritten
+// (see patchStatus()).
+func (r *BasicObject) updateStatus() {
+	r.addDeclaredConditions()
+	if err := r.patchStatus(); err != nil {
+		// Do not track this error but requeue
+		r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultStatusUpdateError, nil, requeueDurationOnStatusUpdateError)
+		return
+	}
+	r.snapshotCR()
+	r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultStatusUpdated)
+}
+
+// addDeclaredConditions Add the declared conditions not yet set as Unknown. They are not tracked as set (see rebaseStatus()).
+func (r *BasicObject) addDeclaredConditions() {
+	if r.managedStatusConditions == nil {
+		return
+	}
+	for _, condType := range r.declaredConditions {
+		if r.GetCondition(condType) == nil {
+			cond := v1.Condition{Type: condType, Status: v1.ConditionUnknown, Reason: "NotSet", ObservedGeneration: r.cr.GetGeneration()}
+			k8scond.SetStatusCondition(r.managedStatusConditions, cond)
+		}
+	}
+}
+
+// patchStatus Patch the status of the CR with the changes made since it was fetched, with an optimistic lock. As the patch replaces
+// the whole conditions list, a CR modified meanwhile is fetched again to apply the changes on its latest version (see rebaseStatus())
+// and the patch is retried. The status is updated if the CR can not be copied.
+func (r *BasicObject) patchStatus() error {
+	if r.crSnapshot == nil {
+		return r.Client.Status().Update(r.GetContext(), r.cr)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Client.Status().Patch(r.GetContext(), r.cr, client.MergeFromWithOptions(r.crSnapshot, client.MergeFromWithOptimisticLock{}))
+		if k8serrors.IsConflict(err) {
+			if errRebase := r.rebaseStatus(); errRebase != nil {
+				return errRebase
+			}
+		}
+		return err
+	})
+}
+
+// rebaseStatus Fetch the latest version of the CR and apply on it the status changes made since the CR was fetched: the fields of the
+// status changed and, one by one, the conditions set. Thus the conditions set by others meanwhile are kept.
+func (r *BasicObject) rebaseStatus() error {
+	previous := r.crSnapshot
+	changed, ok := r.cr.DeepCopyObject().(client.Object)
+	if !ok {
+		return errors.New("the CR can not be copied")
+	}
+	var conditions []v1.Condition
+	for _, condType := range r.conditionsSet {
+		if cond := r.GetCondition(condType); cond != nil {
+			conditions = append(conditions, *cond)
+		}
+	}
+
+	// The CR is reset as the decoding may keep the fields missing in its latest version
+	key := client.ObjectKeyFromObject(r.cr)
+	gvk := r.cr.GetObjectKind().GroupVersionKind()
+	if cr := reflect.ValueOf(r.cr).Elem(); cr.Kind() == reflect.Struct {
+		cr.Set(reflect.Zero(cr.Type()))
+	}
+	r.cr.GetObjectKind().SetGroupVersionKind(gvk)
+	if err := r.Client.Get(r.GetContext(), key, r.cr); err != nil {
+		return err
+	}
+	r.snapshotCR()
+
+	conditionsField := -1
+	if len(r.statusConditionsIndex) > 0 {
+		conditionsField = r.statusConditionsIndex[len(r.statusConditionsIndex)-1]
+	}
+	if latest, from, to := r.statusValue(r.cr), r.statusValue(previous), r.statusValue(changed); latest.IsValid() && from.IsValid() && to.IsValid() {
+		for i := 0; i < latest.NumField(); i++ {
+			if i == conditionsField || !latest.Field(i).CanSet() {
+				continue
+			}
+			if !reflect.DeepEqual(from.Field(i).Interface(), to.Field(i).Interface()) {
+				latest.Field(i).Set(to.Field(i))
+			}
+		}
+	}
+
+	for _, cond := range conditions {
+		k8scond.SetStatusCondition(r.managedStatusConditions, cond)
+	}
+	r.addDeclaredConditions()
+	return nil
+}
+
+// snapshotCR Keep a copy of the CR, as on the Cluster, to patch its status with the changes made since then
+func (r *BasicObject) snapshotCR() {
+	r.crSnapshot, _ = r.cr.DeepCopyObject().(client.Object)
+}
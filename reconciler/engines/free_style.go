@@ -15,6 +15,9 @@
 package engines
 
 import (
+	"context"
+	"fmt"
+
 	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
 	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
 	"github.com/go-logr/logr"
@@ -25,16 +28,24 @@ type FreeStyleHook interface {
 	ReconcileWithCR()
 }
 
+// FreeStyleContextHook is a FreeStyleHook called with the context of the request being served (cancellation, deadline of the
+// reconciliation timeout), instead of ReconcileWithCR()
+type FreeStyleContextHook interface {
+	FreeStyleHook
+	ReconcileWithContext(ctx context.Context)
+}
+
 // FreeStyle reconciler engine type. Let you manage your own reconciliation process as you want.
 // Only the CR is fetched from Cluster before calling the Start() method
 type FreeStyle struct {
 	logr.Logger
 	okterr.Results
+	ctx  context.Context
 	hook FreeStyleHook
 }
 
 // Blank assignement to check type
-var _ oktreconciler.Engine = &FreeStyle{}
+var _ oktreconciler.ConcurrentEngine = &FreeStyle{}
 
 // SetLogger provides the logger to use
 func (e *FreeStyle) SetLogger(logr logr.Logger) {
@@ -46,14 +57,44 @@ func (e *FreeStyle) SetResults(results okterr.Results) {
 	e.Results = results
 }
 
+// SetContext provides the context of the request to serve
+func (e *FreeStyle) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// GetContext Return the context of the request being served (context.TODO() if none), to use with the clients in the hook
+func (e *FreeStyle) GetContext() context.Context {
+	if e.ctx == nil {
+		return context.TODO()
+	}
+	return e.ctx
+}
+
 // Run starts a reconciliation loop
 // All results (errors, operations, ...) have to be collected in Reconciler's results (Results)data
+// A FreeStyleContextHook is called with the context of the request.
 func (e *FreeStyle) Run() {
-	if e.hook != nil {
+	if hook, ok := e.hook.(FreeStyleContextHook); ok {
+		hook.ReconcileWithContext(e.GetContext())
+	} else if e.hook != nil {
 		e.hook.ReconcileWithCR()
 	}
 }
 
+// GetHook Return the hook called by this engine
+func (e *FreeStyle) GetHook() interface{} {
+	return e.hook
+}
+
+// Copy Return a new FreeStyle engine calling the hook provided. The hook must be a FreeStyleHook.
+func (e *FreeStyle) Copy(hook interface{}) (oktreconciler.Engine, error) {
+	freeStyleHook, ok := hook.(FreeStyleHook)
+	if !ok {
+		return nil, fmt.Errorf("the hook is not a FreeStyleHook: %T", hook)
+	}
+	return NewFreeStyle(freeStyleHook), nil
+}
+
 // NewFreeStyle creates a reconciler allowing a free reconciliation. However, the hook passed as argument,
 // is called after CR is fetched (or not) from the cluster
 func NewFreeStyle(hook FreeStyleHook) *FreeStyle {
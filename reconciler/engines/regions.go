@@ -0,0 +1,256 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package engines
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	oktsm "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/statemachine"
+)
+
+// Region is an independent part of the reconciliation, i.e. the frontend tier or the database tier of an application, run in parallel
+// with the other regions of a Stepper (see StepperBuilder.AddRegions()). A region is a sequence of steps which stops at the first step
+// reporting an error (or a give-up) in the region's results.
+type Region struct {
+	Name  string
+	Steps []RegionStep
+}
+
+// RegionStep is a step of a region. The handler is either:
+//   - a method expression of the hook's type, i.e. (*MyReconciler).UpdateFrontend for a method "func (r *MyReconciler) UpdateFrontend(run *RegionRun) error",
+//     called on the reconciler serving the request (see ConcurrentEngine)
+//   - any "func(*RegionRun) error", which does not depend on the reconciler
+//
+// The error returned, if any, is reported in the region's results as OperationResultStateActionError.
+type RegionStep struct {
+	Name    string
+	Handler interface{}
+}
+
+var regionRunType = reflect.TypeOf(&RegionRun{})
+
+// Names of the leaf states of a region
+const (
+	regionDoneState   = "Done"
+	regionFailedState = "Failed"
+)
+
+// compiledRegion is a region whose graph is built and handlers checked
+type compiledRegion struct {
+	name     string
+	graph    oktsm.LCGGraph
+	handlers []reflect.Value // Indexed by the states of the steps
+}
+
+// RegionRun is the run of a region during a reconciliation, passed to the handlers of its steps. It has its own results, merged in the
+// reconciler's results at the join state, and its own logger (with the region name).
+// The regions run concurrently: in a region, report the operations in the region's results, not in the reconciler's ones, and do not
+// register resources. The client and the resources registered before the regions can be used. To use the reconciler's helpers, call them
+// on a copy reporting in the region's results, i.e. r.WithResults(run.Results).Update(resource).
+// A panic in a step is recovered and reported in the region's results as OperationResultStateActionError.
+type RegionRun struct {
+	logr.Logger
+	okterr.Results
+
+	region  *compiledRegion
+	hook    StepperEngineHook
+	ctx     context.Context
+	machine *oktsm.Machine
+}
+
+var _ oktsm.LCGStateAction = &RegionRun{}
+
+// GetName Return the name of the region
+func (run *RegionRun) GetName() string {
+	return run.region.name
+}
+
+// GetState Return the name of the current step
+func (run *RegionRun) GetState() string {
+	return run.region.graph.StateName(run.machine.GetState())
+}
+
+// GetHook Return the reconciler serving the request
+func (run *RegionRun) GetHook() StepperEngineHook {
+	return run.hook
+}
+
+// GetContext Return the context of the request being served (context.TODO() if none), to use with the clients
+func (run *RegionRun) GetContext() context.Context {
+	if run.ctx == nil {
+		return context.TODO()
+	}
+	return run.ctx
+}
+
+// GetHistory Return the steps of the region entered during this run, with their timing
+func (run *RegionRun) GetHistory() []oktsm.LCGStep {
+	return run.machine.GetHistory()
+}
+
+// GetPathInGraph Return the path of this run, i.e. ">Mutator>Updater>Done"
+func (run *RegionRun) GetPathInGraph() string {
+	return run.machine.GetPathInGraph()
+}
+
+// Enter LCGStateAction implementation: call the handler of the step
+func (run *RegionRun) Enter(state oktsm.LCGState) error {
+	if int(state) >= len(run.region.handlers) {
+		return nil // Done or Failed
+	}
+
+	handler := run.region.handlers[state]
+	args := []reflect.Value{reflect.ValueOf(run)}
+	if handler.Type().NumIn() == 2 {
+		args = append([]reflect.Value{reflect.ValueOf(run.hook)}, args...)
+	}
+	if out := handler.Call(args); len(out) == 1 && !out[0].IsNil() {
+		run.AddOp(nil, okterr.OperationResultStateActionError, out[0].Interface().(error), 0)
+	}
+	return nil
+}
+
+// run Enter the steps up to the Done state, or the Failed state on error or when the context is done
+func (run *RegionRun) run() {
+	failed, _ := run.region.graph.State(regionFailedState)
+
+	run.machine.EnablePathInGraph()
+	run.machine.SetState(0)
+	for state := run.machine.GetState(); !run.region.graph.IsLeafNode(state); state = run.machine.GetState() {
+		events := oktsm.LCGEvents{oktsm.DefaultState}
+		if run.ErrorsCount() > 0 || (run.ctx != nil && run.ctx.Err() != nil) {
+			events = oktsm.LCGEvents{failed}
+		}
+		if entered, _ := run.machine.EnterNextState(events); !entered {
+			return
+		}
+	}
+}
+
+// recoverPanic Report a panic of the step being run, if any, as an error of the region: the region stops and the others go on
+func (run *RegionRun) recoverPanic() {
+	if r := recover(); r != nil {
+		run.AddOp(nil, okterr.OperationResultStateActionError, fmt.Errorf("region %q: panic in step %q: %v", run.GetName(), run.GetState(), r), 0)
+	}
+}
+
+// compileRegion Build the graph of a region, a step after the other, and check the handlers regarding the hook's type
+func compileRegion(region *Region, hook StepperEngineHook) (*compiledRegion, []string) {
+	errs := []string{}
+	if len(region.Steps) == 0 {
+		return nil, []string{fmt.Sprintf("region %q: no step", region.Name)}
+	}
+
+	done, failed := oktsm.LCGState(len(region.Steps)), oktsm.LCGState(len(region.Steps)+1)
+	c := &compiledRegion{name: region.Name, graph: oktsm.LCGGraph{
+		done:   oktsm.LCGNodeInfo{Name: regionDoneState},
+		failed: oktsm.LCGNodeInfo{Name: regionFailedState},
+	}}
+	names := map[string]bool{regionDoneState: true, regionFailedState: true}
+	for i, step := range region.Steps {
+		if names[step.Name] {
+			errs = append(errs, fmt.Sprintf("region %q: step %q is already defined", region.Name, step.Name))
+		}
+		names[step.Name] = true
+		c.graph[oktsm.LCGState(i)] = oktsm.LCGNodeInfo{Name: step.Name, Children: oktsm.LCGChildren{failed, oktsm.LCGState(i + 1)}}
+
+		handler, err := regionHandlerValue(step.Handler, hook)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("region %q: step %q: %s", region.Name, step.Name, err))
+		}
+		c.handlers = append(c.handlers, handler)
+	}
+	return c, errs
+}
+
+// regionHandlerValue Check the type of a region step handler regarding the hook's type
+func regionHandlerValue(handler interface{}, hook StepperEngineHook) (reflect.Value, error) {
+	value := reflect.ValueOf(handler)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return value, fmt.Errorf("the handler is not a function but a %T", handler)
+	}
+	t := value.Type()
+	switch {
+	case t.NumOut() > 1 || t.NumOut() == 1 && t.Out(0) != errorType:
+	case t.NumIn() == 1 && t.In(0) == regionRunType:
+		return value, nil
+	case t.NumIn() == 2 && t.In(1) == regionRunType && hook != nil && reflect.TypeOf(hook).AssignableTo(t.In(0)):
+		return value, nil
+	}
+	return value, fmt.Errorf("the handler %s is neither a func(*RegionRun) nor a method expression of the hook's type %T", t, hook)
+}
+
+// forkRegions Return the handler of the fork state: it runs the regions in parallel, each with its own results, and waits for them.
+// If the context is done, the results are merged at once as the join state is not entered.
+func forkRegions(regions []*compiledRegion) StepperStateHandler {
+	return func(smc *Stepper) {
+		smc.regions = make([]*RegionRun, 0, len(regions))
+		smc.regionsMerged = false
+
+		var wg sync.WaitGroup
+		for _, region := range regions {
+			run := &RegionRun{
+				Logger:  smc.Logger.WithValues("region", region.name),
+				Results: smc.Results.Fork(),
+				region:  region,
+				hook:    smc.hook,
+				ctx:     smc.ctx,
+			}
+			run.machine = &oktsm.Machine{Graph: region.graph, Actions: run}
+			smc.regions = append(smc.regions, run)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer run.recoverPanic()
+				run.run()
+			}()
+		}
+		wg.Wait()
+
+		if smc.ctx != nil && smc.ctx.Err() != nil {
+			smc.mergeRegions()
+		}
+	}
+}
+
+// joinRegions Return the handler of the join state: it merges the results of the regions in the reconciler's results, in the order of
+// the regions, then calls the hook
+func joinRegions(smc *Stepper) {
+	smc.mergeRegions()
+	smc.hook.EnterInState(smc)
+}
+
+func (smc *Stepper) mergeRegions() {
+	if smc.regionsMerged {
+		return
+	}
+	for _, run := range smc.regions {
+		smc.Results.Merge(run.Results)
+		smc.Logger.V(1).Info("Region "+run.GetName()+": "+run.GetPathInGraph(), "errors", run.ErrorsCount())
+	}
+	smc.regionsMerged = true
+}
+
+// GetRegions Return the runs of the regions during this reconciliation (see StepperBuilder.AddRegions()), i.e. to check them in the join state
+func (smc *Stepper) GetRegions() []*RegionRun {
+	return smc.regions
+}
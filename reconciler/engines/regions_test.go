@@ -0,0 +1,149 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package engines
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+type regionsTestReconciler struct {
+	builderTestReconciler
+
+	lock      sync.Mutex
+	steps     []string
+	dbStarted chan struct{}
+}
+
+func (rec *regionsTestReconciler) trace(run *RegionRun) {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	rec.steps = append(rec.steps, run.GetName()+"/"+run.GetState())
+}
+
+func (rec *regionsTestReconciler) UpdateFrontend(run *RegionRun) error {
+	rec.trace(run)
+	// Wait for the database region, running in parallel
+	select {
+	case <-rec.dbStarted:
+	case <-time.After(5 * time.Second):
+		return errors.New("the regions do not run in parallel")
+	}
+	run.AddOpSuccess(nil, okterr.OperationResultUpdated)
+	return nil
+}
+
+func (rec *regionsTestReconciler) UpdateDatabase(run *RegionRun) error {
+	rec.trace(run)
+	close(rec.dbStarted)
+	return errors.New("database unavailable")
+}
+
+func (rec *regionsTestReconciler) WaitDatabase(run *RegionRun) error {
+	rec.trace(run)
+	return nil
+}
+
+func TestStepperRegions(t *testing.T) {
+	rec := &regionsTestReconciler{dbStarted: make(chan struct{})}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+
+	engine, err := NewStepperBuilder().
+		AddRegions("Fork", "Join", "ObjectsGetter",
+			&Region{Name: "frontend", Steps: []RegionStep{
+				{Name: "Mutator", Handler: func(run *RegionRun) error { rec.trace(run); return nil }},
+				{Name: "Updater", Handler: (*regionsTestReconciler).UpdateFrontend},
+			}},
+			&Region{Name: "database", Steps: []RegionStep{
+				{Name: "Updater", Handler: (*regionsTestReconciler).UpdateDatabase},
+				{Name: "ReadinessWaiter", Handler: (*regionsTestReconciler).WaitDatabase},
+			}}).
+		Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+	engine.SetLogger(logr.Discard())
+
+	engine.Run()
+	require.ElementsMatch(t, []string{"frontend/Mutator", "frontend/Updater", "database/Updater"}, rec.steps, "The database region stops on error")
+	require.Equal(t, []string{"CRChecker", "ObjectsGetter", "Join", "ErrorManager"}, rec.states)
+	require.Equal(t, ">CRChecker>ObjectsGetter>Fork>Join>ErrorManager>End", engine.machine.GetPathInGraph())
+
+	// The results of the regions are merged
+	regions := engine.GetRegions()
+	require.Len(t, regions, 2)
+	require.Equal(t, ">Mutator>Updater>Done", regions[0].GetPathInGraph())
+	require.Equal(t, ">Updater>Failed", regions[1].GetPathInGraph())
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultUpdated))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultStateActionError))
+	_, err = rec.ConsolidatedError()
+	require.EqualError(t, err, "database unavailable")
+}
+
+func TestStepperRegionsValidation(t *testing.T) {
+	rec := &regionsTestReconciler{}
+	step := RegionStep{Name: "Updater", Handler: func(run *RegionRun) error { return nil }}
+
+	testCases := []struct {
+		name     string
+		regions  []*Region
+		expected string
+	}{
+		{"no region", nil, `state "Fork": no region to run`},
+		{"no step", []*Region{{Name: "frontend"}}, `state "Fork": region "frontend": no step`},
+		{"duplicate region", []*Region{{Name: "frontend", Steps: []RegionStep{step}}, {Name: "frontend", Steps: []RegionStep{step}}}, `state "Fork": region "frontend" is already defined`},
+		{"duplicate step", []*Region{{Name: "frontend", Steps: []RegionStep{step, step}}}, `state "Fork": region "frontend": step "Updater" is already defined`},
+		{"reserved step", []*Region{{Name: "frontend", Steps: []RegionStep{{Name: "Done", Handler: step.Handler}}}}, `region "frontend": step "Done" is already defined`},
+		{"handler type", []*Region{{Name: "frontend", Steps: []RegionStep{{Name: "Updater", Handler: func(engine *Stepper) {}}}}}, `region "frontend": step "Updater": the handler func(*engines.Stepper) is neither`},
+	}
+	for _, tc := range testCases {
+		_, err := NewStepperBuilder().AddRegions("Fork", "Join", "ObjectsGetter", tc.regions...).Build(rec)
+		require.Error(t, err, tc.name)
+		require.Contains(t, err.Error(), tc.expected, tc.name)
+	}
+}
+
+func TestStepperRegionsPanic(t *testing.T) {
+	rec := &regionsTestReconciler{}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+
+	engine, err := NewStepperBuilder().
+		AddRegions("Fork", "Join", "ObjectsGetter",
+			&Region{Name: "frontend", Steps: []RegionStep{
+				{Name: "Updater", Handler: func(run *RegionRun) error { panic("nil map") }},
+				{Name: "ReadinessWaiter", Handler: func(run *RegionRun) error { rec.trace(run); return nil }},
+			}},
+			&Region{Name: "database", Steps: []RegionStep{
+				{Name: "Updater", Handler: func(run *RegionRun) error { rec.trace(run); return nil }},
+			}}).
+		Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+	engine.SetLogger(logr.Discard())
+
+	engine.Run()
+	require.Equal(t, []string{"database/Updater"}, rec.steps, "The region stops on panic, the others go on")
+	require.Equal(t, []string{"CRChecker", "ObjectsGetter", "Join", "ErrorManager"}, rec.states)
+	_, err = rec.ConsolidatedError()
+	require.EqualError(t, err, `region "frontend": panic in step "Updater": nil map`)
+}
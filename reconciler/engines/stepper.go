@@ -12,17 +12,25 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.package apis
 
-/**
-  This file is intended to replace the reconciler engine file in `reconciler/engine/stepper.go`
-  For the moment the test does not pass since a weird behaviour of the Build() function that should be fixed quickly.
+/*
+*
 
-  The Graph below is an example of LCGraph and Reconciliation states.
-**/
+	This file is intended to replace the reconciler engine file in `reconciler/engine/stepper.go`
+	For the moment the test does not pass since a weird behaviour of the Build() function that should be fixed quickly.
+
+	The Graph below is an example of LCGraph and Reconciliation states.
+
+*
+*/
 package engines
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"time"
 
+	oktmetrics "github.com/Orange-OpenSource/Operators-Karma-Tools/metrics"
 	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
 	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
 	oktsm "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/statemachine"
@@ -47,9 +55,10 @@ type hookCaller interface {
 func giveUpStateHook(ctx interface{}) error {
 	smc := ctx.(*Stepper)
 	//smc.Logger.Info("------- GIVEN UP reconciliation state reached -------")
-	smc.Logger.Info(okterr.ErrGiveUpReconciliation.Error())
+	_, err := smc.ConsolidatedError()
+	smc.Logger.Info(okterr.ErrGiveUpReconciliation.Reason(err).Error())
 
-	if _, err := smc.ConsolidatedError(); err != nil {
+	if err != nil {
 		smc.Logger.Error(err, "Something is missing or definitively wrong. Give up this reconciliation")
 	}
 	return nil
@@ -65,40 +74,63 @@ func endStateHook(ctx interface{}) error {
 // It can be used to implement an idempotent Reconcile function for an Operator Controller
 //
 // Reconciliation states :
-//      CRChecker           // Check the Custom Resource received by the Controller (from the queue)
-//      ObjectsGetter       // Create all OKT resources types manipulated by this Operator, register them to the OKT Reconciler
-//      Mutator			    // Mutates OKT resources to the desired state
-//      Updater				// Create or Updates OKT resources on Cluster
-//		SuccessManager		// Is reached after the Updater state in case of success
 //
-//  Here are the debranching steps occuring on Finalization, Error, or Give-up events:
-//		CRFinalizer			// The CR is being deleted and has finalizer. This stage allows you to manage your own cleanup logic. The CR update is managed at the OKT reconciler level.
-//		ErrorManager		// Is reached after any step that raise an error different than GiveUpError. Just add an error in your Reconciler's results.
+//	     CRChecker           // Check the Custom Resource received by the Controller (from the queue)
+//	     ObjectsGetter       // Create all OKT resources types manipulated by this Operator, register them to the OKT Reconciler
+//	     Mutator			    // Mutates OKT resources to the desired state
+//	     Updater				// Create or Updates OKT resources on Cluster
+//			SuccessManager		// Is reached after the Updater state in case of success
 //
-//  Here is a special debranching step that is managed internaly by the Stepper, no need to handle it at your end
-//      GiveUpManager		// A Terminal state. Can be reached at any time/in any state (even in SuccessManager) when an unrecoverable error happen or simply when the reconciliation can't go further for the moment
+//	 Here are the debranching steps occuring on Finalization, Error, or Give-up events:
+//			CRFinalizer			// The CR is being deleted and has finalizer. This stage allows you to manage your own cleanup logic (see DeleteAllResources() for resources not owned by the CR). The CR update is managed at the OKT reconciler level.
+//			ErrorManager		// Is reached after any step that raise an error different than GiveUpError. Just add an error in your Reconciler's results.
+//
+//	 Here is a special debranching step that is managed internaly by the Stepper, no need to handle it at your end
+//	     GiveUpManager		// A Terminal state. Can be reached at any time/in any state (even in SuccessManager) when an unrecoverable error happen or simply when the reconciliation can't go further for the moment
+//
+// When the context of the request is done (reconciliation timeout exceeded), the OperationResultReconcileTimeout error is reported and
+// the Stepper goes directly to the GiveUpManager state, without calling the hook anymore. The request is requeued as for any error.
 //
 // Besides that, use the "Free style" engine if you want a full control on the reconiliation process.
 type Stepper struct {
 	logr.Logger
 	okterr.Results
+	ctx context.Context
 
 	machine *oktsm.Machine
+	graph   oktsm.LCGGraph
+
+	hook     StepperEngineHook
+	handlers map[oktsm.LCGState]reflect.Value // Handlers of the states, instead of the hook (see StepperBuilder)
 
-	hook StepperEngineHook
+	// Name of the controller used as label of the states duration metric, empty when disabled
+	metricsController string
+
+	// Runs of the regions during this reconciliation, if any (see StepperBuilder.AddRegions())
+	regions       []*RegionRun
+	regionsMerged bool
 }
 
 // blank assignment to verify that ReconcileCockroachDB implements reconcile.Reconciler
-var _ oktreconciler.Engine = &Stepper{}
+var _ oktreconciler.ConcurrentEngine = &Stepper{}
+var _ oktreconciler.MeasurableEngine = &Stepper{}
 var _ oktsm.LCGStateAction = &Stepper{}
 
 // GetState transition.Stater implementation
 func (smc *Stepper) GetState() string {
-	return recGraph[smc.machine.GetState()].Name
+	return smc.graph.StateName(smc.machine.GetState())
 }
 
 func (smc *Stepper) Enter(state oktsm.LCGState) error {
 	//state := smc.machine.GetState()
+	if smc.metricsController != "" {
+		start := time.Now()
+		defer func() { oktmetrics.ObserveState(smc.metricsController, smc.graph.StateName(state), time.Since(start)) }()
+	}
+
+	if handler, exists := smc.handlers[state]; exists {
+		return smc.callHandler(handler)
+	}
 
 	switch state {
 	case GiveupManager:
@@ -197,6 +229,11 @@ var recGraph = oktsm.LCGGraph{
 // Determine which is the next state after the current one and regarding the case of error or not and the Givenup case if any.
 // Return the event name that will be triggered to go to the next state.
 func (smc *Stepper) eventsList(curState oktsm.LCGState) (events oktsm.LCGEvents) {
+	// Timeout ? Abort without any other step
+	if smc.isTimedOut() {
+		return oktsm.LCGEvents{GiveupManager, oktsm.DefaultState}
+	}
+
 	giveup, err := smc.Results.ConsolidatedError()
 	finalizing := smc.Results.OpsCount(okterr.OperationResultCRIsFinalizing) == 1
 
@@ -221,10 +258,45 @@ func (smc *Stepper) eventsList(curState oktsm.LCGState) (events oktsm.LCGEvents)
 	return events
 }
 
-// DisplayPathOfStates log the path of states
+// isTimedOut Tells if the context of the request is done and report it (once)
+func (smc *Stepper) isTimedOut() bool {
+	if smc.ctx == nil || smc.ctx.Err() == nil {
+		return false
+	}
+	if smc.OpsCount(okterr.OperationResultReconcileTimeout) == 0 {
+		smc.AddOp(nil, okterr.OperationResultReconcileTimeout, smc.ctx.Err(), 0)
+	}
+	return true
+}
+
+// DisplayPathOfStates log the path of states, and the duration of each state at verbosity level 1
 func (smc *Stepper) DisplayPathOfStates(logs logr.Logger) {
 	path := "History: " + smc.machine.GetPathInGraph()
 	logs.Info(path)
+
+	history := smc.machine.GetHistory()
+	durations := make([]string, 0, len(history))
+	for _, step := range history {
+		if step.Duration > 0 {
+			durations = append(durations, step.Name+"="+step.Duration.String())
+		}
+	}
+	logs.V(1).Info("States duration", "states", durations)
+}
+
+// GetGraph Return the reconciliation graph of this engine, i.e. to render it (see LCGGraph.ToDOT() and ToMermaid())
+func (smc *Stepper) GetGraph() oktsm.LCGGraph {
+	return smc.graph
+}
+
+// GetHistory Return the steps of the last reconciliation with their entry time, duration, events and error, i.e. to log or export the timings of the states
+func (smc *Stepper) GetHistory() []oktsm.LCGStep {
+	return smc.machine.GetHistory()
+}
+
+// GetPathStates Return the states of the last reconciliation, i.e. to overlay them on the rendered graph
+func (smc *Stepper) GetPathStates() []oktsm.LCGState {
+	return smc.machine.GetPathStates()
 }
 
 // SetLogger xx
@@ -237,6 +309,38 @@ func (smc *Stepper) SetResults(res okterr.Results) {
 	smc.Results = res
 }
 
+// SetContext provides the context of the request to serve
+func (smc *Stepper) SetContext(ctx context.Context) {
+	smc.ctx = ctx
+}
+
+// EnableMetrics Observe the duration of each state with the controller name provided as label (see okt metrics.StateDuration)
+func (smc *Stepper) EnableMetrics(controller string) {
+	smc.metricsController = controller
+}
+
+// GetContext Return the context of the request being served (context.TODO() if none), to use with the clients in the hook
+func (smc *Stepper) GetContext() context.Context {
+	if smc.ctx == nil {
+		return context.TODO()
+	}
+	return smc.ctx
+}
+
+// specTracker is a hook able to tell if the CR spec changed, like the OKT reconcilers (see BasicObject.SpecChanged())
+type specTracker interface {
+	SpecChanged() bool
+}
+
+// SpecChanged Tells if the CR spec changed since the last successful reconciliation, i.e. to skip a state when unchanged.
+// Always true if the hook can not tell.
+func (smc *Stepper) SpecChanged() bool {
+	if tracker, ok := smc.hook.(specTracker); ok {
+		return tracker.SpecChanged()
+	}
+	return true
+}
+
 // Run starts from the first reconciliation step (CRChecker).
 // At each an action hook is called with a context parameter which is the Stepper engine itself.
 // It stops either if an error is returned or if the "End" state is reached
@@ -244,18 +348,23 @@ func (smc *Stepper) SetResults(res okterr.Results) {
 // Compute next state. The principle is to generate a list of events based on watched variable (error, giveup, finalizing) and
 // build a list of events. To be sure to go to the next step, the normal course event "DefaultState" is always added to the list.
 func (smc *Stepper) Run() {
-	okterr.ErrGiveUpReconciliation.Reason(nil)
-
 	var infiniteLoopBreaker = 1000 // Security in case of wrong machine state model
 
 	smc.machine.EnablePathInGraph() // Enable path function or reset it to zero /!\
-	smc.machine.SetState(CRChecker) // Enter into First state (without any event condition)
+	// Enter into First state (without any event condition)
+	if _, err := smc.machine.EnterState(CRChecker); err != nil {
+		smc.AddOp(nil, okterr.OperationResultStateActionError, err, 0)
+	}
 
 	// Loop on each step of the state machine and call hooks until an error is raised or an ending state is reached
 	// Ending state: "End"
-	for state := smc.machine.GetState(); !recGraph.IsLeafNode(state); state = smc.machine.GetState() {
+	for state := smc.machine.GetState(); !smc.graph.IsLeafNode(state); state = smc.machine.GetState() {
 		events := smc.eventsList(state)
-		if entered, _ := smc.machine.EnterNextState(events); !entered {
+		entered, err := smc.machine.EnterNextState(events)
+		if err != nil {
+			smc.AddOp(nil, okterr.OperationResultStateActionError, err, 0)
+		}
+		if !entered {
 			return
 		}
 
@@ -267,11 +376,30 @@ func (smc *Stepper) Run() {
 	}
 }
 
+// GetHook Return the hook called by this engine
+func (smc *Stepper) GetHook() interface{} {
+	return smc.hook
+}
+
+// Copy Return a new Stepper engine, with its own state machine, calling the hook provided. The hook must be a StepperEngineHook.
+func (smc *Stepper) Copy(hook interface{}) (oktreconciler.Engine, error) {
+	stepperHook, ok := hook.(StepperEngineHook)
+	if !ok {
+		return nil, fmt.Errorf("the hook is not a StepperEngineHook: %T", hook)
+	}
+	return newStepper(stepperHook, smc.graph, smc.handlers), nil
+}
+
 // NewStepper Allocates a new reconciler Engine that will course several steps that maps a classical Reconciling process
 // See Stepper type documentation
+// Use a StepperBuilder to add your own states to the reconciliation graph.
 func NewStepper(hook StepperEngineHook) *Stepper {
-	s := &Stepper{hook: hook}
-	s.machine = &oktsm.Machine{Graph: recGraph, Actions: s}
+	return newStepper(hook, recGraph, nil)
+}
+
+func newStepper(hook StepperEngineHook, graph oktsm.LCGGraph, handlers map[oktsm.LCGState]reflect.Value) *Stepper {
+	s := &Stepper{hook: hook, graph: graph, handlers: handlers}
+	s.machine = &oktsm.Machine{Graph: graph, Actions: s}
 
 	return s
 }
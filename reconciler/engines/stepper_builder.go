@@ -0,0 +1,338 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package engines
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	oktsm "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/statemachine"
+)
+
+// StepperStateHandler is a handler of a Stepper state which does not depend on the reconciler (see StepperBuilder.Handle())
+type StepperStateHandler func(engine *Stepper)
+
+// StepperStateFunc is a handler of a Stepper state checked at compilation time (see StepperBuilder.HandleFunc()). The reconciler serving the
+// request is engine.GetHook().
+type StepperStateFunc func(engine *Stepper) error
+
+var (
+	stepperType = reflect.TypeOf(&Stepper{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// StepperBuilder builds a Stepper engine with your own states added to the reconciliation graph, i.e. a "BackupTaker" state between the
+// Mutator and the Updater states, and with a handler per state instead of a single EnterInState() hook switching on the state names.
+// The graph is validated when building the engine.
+/* Example:
+
+engine, err := oktengines.NewStepperBuilder().
+	AddState("BackupTaker", "Mutator", (*MyReconciler).TakeBackup).
+	AddState("HealthWaiter", "Updater", (*MyReconciler).WaitHealthy).
+	Handle("ObjectsGetter", (*MyReconciler).GetObjects).
+	Build(r)
+*/
+type StepperBuilder struct {
+	states   []string            // State names, indexed by their LCGState
+	children map[string][]string // Children of the states, by priority order
+	handlers map[string]interface{}
+	regions  map[string][]*Region // Regions run by the fork states
+	waiters  []string             // States waiting for the readiness of the resources
+	errs     []string
+}
+
+// NewStepperBuilder Return a builder starting from the Stepper's reconciliation graph (see Stepper)
+func NewStepperBuilder() *StepperBuilder {
+	b := &StepperBuilder{
+		states:   make([]string, len(recGraph)),
+		children: make(map[string][]string, len(recGraph)),
+		handlers: make(map[string]interface{}),
+		regions:  make(map[string][]*Region),
+	}
+	for state, node := range recGraph {
+		b.states[state] = node.Name
+		for _, child := range node.Children {
+			b.children[node.Name] = append(b.children[node.Name], recGraph[child].Name)
+		}
+	}
+	return b
+}
+
+func (b *StepperBuilder) addError(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+func (b *StepperBuilder) isDefined(name string) bool {
+	_, defined := b.children[name]
+	return defined || name == recGraph[End].Name
+}
+
+// AddState Insert a new state on the normal course of the reconciliation, after the state provided: the new state becomes the default child
+// of this state, and its own children are the ErrorManager, the GiveupManager and the former default child.
+// The handler is called when entering the state (see Handle()), the hook's EnterInState() is called if nil.
+func (b *StepperBuilder) AddState(name, after string, handler interface{}) *StepperBuilder {
+	if b.isDefined(name) {
+		b.addError("state %q: already defined", name)
+		return b
+	}
+	children := b.children[after]
+	if len(children) == 0 {
+		b.addError("state %q: can not be added after %q which is not defined or is a leaf", name, after)
+		return b
+	}
+
+	b.states = append(b.states, name)
+	b.children[name] = []string{recGraph[ErrorManager].Name, recGraph[GiveupManager].Name, children[len(children)-1]}
+	b.children[after] = append(append([]string{}, children[:len(children)-1]...), name)
+
+	if handler != nil {
+		b.Handle(name, handler)
+	}
+	return b
+}
+
+// AddRegions Insert a fork state after the state provided, followed by a join state (see AddState()). The fork state runs the regions
+// provided in parallel, each with its own results (see Region and RegionRun). The join state merges the results of the regions in the
+// reconciler's results, then calls the hook's EnterInState(): the errors of the regions are routed to the ErrorManager from there.
+/* Example:
+
+engine, err := oktengines.NewStepperBuilder().
+	AddRegions("Fork", "Join", "ObjectsGetter",
+		&oktengines.Region{Name: "frontend", Steps: []oktengines.RegionStep{
+			{Name: "Mutator", Handler: (*MyReconciler).MutateFrontend},
+			{Name: "Updater", Handler: (*MyReconciler).UpdateFrontend},
+		}},
+		&oktengines.Region{Name: "database", Steps: []oktengines.RegionStep{
+			{Name: "Updater", Handler: (*MyReconciler).UpdateDatabase},
+			{Name: "ReadinessWaiter", Handler: (*MyReconciler).WaitDatabase},
+		}}).
+	Build(r)
+*/
+func (b *StepperBuilder) AddRegions(fork, join, after string, regions ...*Region) *StepperBuilder {
+	if len(regions) == 0 {
+		b.addError("state %q: no region to run", fork)
+		return b
+	}
+	b.AddState(fork, after, nil).AddState(join, fork, StepperStateHandler(joinRegions))
+	b.regions[fork] = regions
+	return b
+}
+
+// readinessWaiter is a hook able to check the readiness of its registered resources, like the OKT reconcilers (see BasicObject.WaitReady())
+type readinessWaiter interface {
+	WaitReady() bool
+}
+
+// waitReady Check the readiness of the resources registered by the hook
+func waitReady(smc *Stepper) {
+	smc.hook.(readinessWaiter).WaitReady()
+}
+
+// AddWaitReady Insert a state after the state provided (see AddState()), typically after the Updater state, checking the readiness of all the
+// resources registered by the reconciler (see BasicObject.WaitReady()). While resources are not ready, the reconciliation goes on and is
+// requeued later. The hook must provide a WaitReady() method, as the OKT reconcilers.
+func (b *StepperBuilder) AddWaitReady(name, after string) *StepperBuilder {
+	b.AddState(name, after, StepperStateHandler(waitReady))
+	b.waiters = append(b.waiters, name)
+	return b
+}
+
+// SetChildren Replace the children of a state, by priority order. The last one is the default child, entered on the normal course.
+// A state not yet defined is added.
+func (b *StepperBuilder) SetChildren(name string, children ...string) *StepperBuilder {
+	if name == recGraph[End].Name {
+		b.addError("state %q: the end of the reconciliation can not have children", name)
+		return b
+	}
+	if !b.isDefined(name) {
+		b.states = append(b.states, name)
+	}
+	b.children[name] = append([]string{}, children...)
+	return b
+}
+
+// Handle Set the handler called when entering a state, instead of the hook's EnterInState(). The handler is either:
+//   - a method expression of the hook's type, i.e. (*MyReconciler).TakeBackup for a method "func (r *MyReconciler) TakeBackup(engine *Stepper)",
+//     called on the reconciler serving the request (see ConcurrentEngine)
+//   - a StepperStateHandler, or any "func(*Stepper)", which does not depend on the reconciler
+//
+// The handler may return an error, then reported as OperationResultStateActionError, i.e. "func (r *MyReconciler) TakeBackup(engine *Stepper) error".
+// The GiveupManager and End states are managed by the Stepper and can not be handled.
+func (b *StepperBuilder) Handle(name string, handler interface{}) *StepperBuilder {
+	if name == recGraph[GiveupManager].Name || name == recGraph[End].Name {
+		b.addError("state %q: managed by the Stepper, can not be handled", name)
+		return b
+	}
+	b.handlers[name] = handler
+	return b
+}
+
+// AddStateFunc Insert a new state after the state provided, as AddState() does, with a typed handler (see HandleFunc())
+func (b *StepperBuilder) AddStateFunc(name, after string, handler StepperStateFunc) *StepperBuilder {
+	if handler == nil {
+		return b.AddState(name, after, nil)
+	}
+	return b.AddState(name, after, handler)
+}
+
+// HandleFunc Set the handler called when entering a state, as Handle() does, with a handler whose type is checked at compilation time.
+// Get the reconciler serving the request with engine.GetHook(): a method value (i.e. r.TakeBackup) would be bound to the reconciler it
+// was taken from, not to the copy serving the request (see ConcurrentEngine).
+func (b *StepperBuilder) HandleFunc(name string, handler StepperStateFunc) *StepperBuilder {
+	return b.Handle(name, handler)
+}
+
+// Build Validate the graph and return a new Stepper engine calling the hook provided for the states without handler.
+// The graph must satisfy:
+//   - the children of the states are defined
+//   - End is the only leaf state, thus each other state has a default child
+//   - each state, except the ErrorManager, GiveupManager and End ones, has the ErrorManager and the GiveupManager as children
+//   - each state is reachable from CRChecker and can reach End, without cycle closed on itself (see oktsm.LCGGraph.Validate())
+//   - the handlers are of a type described in Handle()
+func (b *StepperBuilder) Build(hook StepperEngineHook) (*Stepper, error) {
+	errs := append([]string{}, b.errs...)
+	addError := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	ids := make(map[string]oktsm.LCGState, len(b.states))
+	for id, name := range b.states {
+		ids[name] = oktsm.LCGState(id)
+	}
+
+	graph := make(oktsm.LCGGraph, len(b.states))
+	for id, name := range b.states {
+		node := oktsm.LCGNodeInfo{Name: name}
+		for _, child := range b.children[name] {
+			childID, defined := ids[child]
+			if !defined {
+				addError("state %q: child %q is not defined", name, child)
+				continue
+			}
+			node.Children = append(node.Children, childID)
+		}
+		graph[oktsm.LCGState(id)] = node
+
+		if name == recGraph[End].Name {
+			continue
+		}
+		if len(b.children[name]) == 0 {
+			addError("state %q: no default child, only End can be a leaf", name)
+		}
+		if state := oktsm.LCGState(id); state != ErrorManager && state != GiveupManager {
+			for _, required := range []oktsm.LCGState{ErrorManager, GiveupManager} {
+				if !containsState(node.Children, required) {
+					addError("state %q: %s is not one of its children", name, recGraph[required].Name)
+				}
+			}
+		}
+	}
+
+	if err := graph.Validate(CRChecker); err != nil {
+		var issues oktsm.LCGGraphErrors
+		if !errors.As(err, &issues) {
+			return nil, err
+		}
+		for _, issue := range issues {
+			addError("%s", issue.Error())
+		}
+	}
+
+	handlers := make(map[oktsm.LCGState]reflect.Value, len(b.handlers))
+	for name, handler := range b.handlers {
+		id, defined := ids[name]
+		if !defined {
+			addError("state %q: handled but not defined", name)
+			continue
+		}
+		value, err := handlerValue(handler, hook)
+		if err != nil {
+			addError("state %q: %s", name, err)
+			continue
+		}
+		handlers[id] = value
+	}
+
+	for fork, regions := range b.regions {
+		compiled := make([]*compiledRegion, 0, len(regions))
+		names := make(map[string]bool, len(regions))
+		for _, region := range regions {
+			if names[region.Name] {
+				addError("state %q: region %q is already defined", fork, region.Name)
+			}
+			names[region.Name] = true
+			c, regionErrs := compileRegion(region, hook)
+			for _, err := range regionErrs {
+				addError("state %q: %s", fork, err)
+			}
+			compiled = append(compiled, c)
+		}
+		if id, defined := ids[fork]; defined {
+			handlers[id] = reflect.ValueOf(forkRegions(compiled))
+		}
+	}
+
+	for _, name := range b.waiters {
+		if _, ok := hook.(readinessWaiter); !ok {
+			addError("state %q: the hook %T has no WaitReady() method", name, hook)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid Stepper graph: %s", strings.Join(errs, "; "))
+	}
+	return newStepper(hook, graph, handlers), nil
+}
+
+// handlerValue Check the type of a state handler regarding the hook's type
+func handlerValue(handler interface{}, hook StepperEngineHook) (reflect.Value, error) {
+	value := reflect.ValueOf(handler)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return value, fmt.Errorf("the handler is not a function but a %T", handler)
+	}
+	t := value.Type()
+	switch {
+	case t.NumOut() > 1 || t.NumOut() == 1 && t.Out(0) != errorType:
+	case t.NumIn() == 1 && t.In(0) == stepperType:
+		return value, nil
+	case t.NumIn() == 2 && t.In(1) == stepperType && hook != nil && reflect.TypeOf(hook).AssignableTo(t.In(0)):
+		return value, nil
+	}
+	return value, fmt.Errorf("the handler %s is neither a func(*Stepper) nor a method expression of the hook's type %T", t, hook)
+}
+
+// callHandler Call the handler of a state (see StepperBuilder.Handle()) and return its error, if any
+func (smc *Stepper) callHandler(handler reflect.Value) error {
+	args := []reflect.Value{reflect.ValueOf(smc)}
+	if handler.Type().NumIn() == 2 {
+		args = append([]reflect.Value{reflect.ValueOf(smc.hook)}, args...)
+	}
+	out := handler.Call(args)
+	if len(out) == 0 || out[0].IsNil() {
+		return nil
+	}
+	return out[0].Interface().(error)
+}
+
+func containsState(states oktsm.LCGChildren, state oktsm.LCGState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
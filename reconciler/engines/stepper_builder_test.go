@@ -0,0 +1,187 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package engines
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	oktsm "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/statemachine"
+)
+
+type builderTestReconciler struct {
+	oktreconciler.AdvancedObject
+	states []string
+}
+
+func (rec *builderTestReconciler) EnterInState(engine *Stepper) {
+	rec.states = append(rec.states, engine.GetState())
+}
+
+func (rec *builderTestReconciler) TakeBackup(engine *Stepper) {
+	rec.states = append(rec.states, "backup taken in "+engine.GetState())
+}
+
+func TestStepperBuilder(t *testing.T) {
+	rec := &builderTestReconciler{}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+
+	var waited bool
+	engine, err := NewStepperBuilder().
+		AddState("BackupTaker", "Mutator", (*builderTestReconciler).TakeBackup).
+		AddState("HealthWaiter", "Updater", StepperStateHandler(func(engine *Stepper) { waited = true })).
+		AddState("SchemaMigrator", "BackupTaker", nil).
+		Build(rec)
+	require.NoError(t, err)
+	require.NoError(t, recGraph.Validate(CRChecker))
+	require.NoError(t, engine.GetGraph().Validate(CRChecker))
+	rec.SetEngine(engine)
+
+	engine.Run()
+	require.Equal(t, []string{"CRChecker", "ObjectsGetter", "Mutator", "backup taken in BackupTaker", "SchemaMigrator", "Updater", "SuccessManager"}, rec.states)
+	require.True(t, waited)
+	require.Equal(t, ">CRChecker>ObjectsGetter>Mutator>BackupTaker>SchemaMigrator>Updater>HealthWaiter>SuccessManager>End", engine.machine.GetPathInGraph())
+
+	// The copy of the engine keeps the graph and calls the handlers on the hook provided
+	copyRec := &builderTestReconciler{}
+	copyRec.Log = logr.Discard()
+	copyRec.Init("test", nil, nil)
+	copyEngine, err := engine.Copy(copyRec)
+	require.NoError(t, err)
+	copyRec.SetEngine(copyEngine)
+	copyEngine.Run()
+	require.Contains(t, copyRec.states, "backup taken in BackupTaker")
+}
+
+func TestStepperBuilderValidation(t *testing.T) {
+	rec := &builderTestReconciler{}
+
+	testCases := []struct {
+		name     string
+		builder  *StepperBuilder
+		expected string
+	}{
+		{"unknown previous state", NewStepperBuilder().AddState("BackupTaker", "Unknown", nil), `state "BackupTaker": can not be added after "Unknown"`},
+		{"duplicate", NewStepperBuilder().AddState("Mutator", "ObjectsGetter", nil), `state "Mutator": already defined`},
+		{"dangling child", NewStepperBuilder().SetChildren("Mutator", "ErrorManager", "GiveupManager", "Missing"), `state "Mutator": child "Missing" is not defined`},
+		{"no default child", NewStepperBuilder().SetChildren("Mutator"), `state "Mutator": no default child`},
+		{"no giveup", NewStepperBuilder().SetChildren("Mutator", "ErrorManager", "Updater"), `state "Mutator": GiveupManager is not one of its children`},
+		{"unreachable", NewStepperBuilder().SetChildren("Orphan", "ErrorManager", "GiveupManager", "End"), `state "Orphan": unreachable: not reachable from CRChecker`},
+		{"no end", NewStepperBuilder().SetChildren("ErrorManager", "GiveupManager").SetChildren("GiveupManager", "ErrorManager"), `state "ErrorManager": no leaf reachable`},
+		{"handler type", NewStepperBuilder().Handle("Mutator", func(engine *FreeStyle) {}), `state "Mutator": the handler func(*engines.FreeStyle) is neither`},
+		{"internal state", NewStepperBuilder().Handle("GiveupManager", StepperStateHandler(func(engine *Stepper) {})), `state "GiveupManager": managed by the Stepper`},
+	}
+	for _, tc := range testCases {
+		_, err := tc.builder.Build(rec)
+		require.Error(t, err, tc.name)
+		require.Contains(t, err.Error(), tc.expected, tc.name)
+	}
+}
+
+// plainHook is a hook which is not an OKT reconciler
+type plainHook struct{}
+
+func (plainHook) EnterInState(engine *Stepper) {}
+
+func TestStepperBuilderWaitReady(t *testing.T) {
+	rec := &builderTestReconciler{}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+
+	engine, err := NewStepperBuilder().AddWaitReady("ReadinessWaiter", "Updater").Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+
+	engine.Run()
+	require.Equal(t, ">CRChecker>ObjectsGetter>Mutator>Updater>ReadinessWaiter>SuccessManager>End", engine.machine.GetPathInGraph())
+	require.NotContains(t, rec.states, "ReadinessWaiter", "Handled by the Stepper")
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultNotReady))
+
+	_, err = NewStepperBuilder().AddWaitReady("ReadinessWaiter", "Updater").Build(plainHook{})
+	require.EqualError(t, err, `invalid Stepper graph: state "ReadinessWaiter": the hook engines.plainHook has no WaitReady() method`)
+}
+
+func (rec *builderTestReconciler) FailBackup(engine *Stepper) error {
+	return errors.New("backup failed")
+}
+
+func TestStepperHandlerError(t *testing.T) {
+	rec := &builderTestReconciler{}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+
+	engine, err := NewStepperBuilder().AddState("BackupTaker", "Mutator", (*builderTestReconciler).FailBackup).Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+
+	engine.Run()
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultStateActionError))
+	_, err = rec.ConsolidatedError()
+	require.EqualError(t, err, "backup failed")
+	require.Equal(t, ">CRChecker>ObjectsGetter>Mutator>BackupTaker>ErrorManager>End", engine.machine.GetPathInGraph())
+
+	history := engine.GetHistory()
+	require.Len(t, history, 6)
+	require.Equal(t, "BackupTaker", history[3].Name)
+	require.EqualError(t, history[3].Err, "backup failed")
+	require.True(t, history[4].Events.Contains(ErrorManager), "Entered on the error event")
+	for _, step := range history {
+		require.False(t, step.EnteredAt.IsZero())
+	}
+}
+
+func TestStepperBuilderTypedHandlers(t *testing.T) {
+	rec := &builderTestReconciler{}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+
+	engine, err := NewStepperBuilder().
+		AddStateFunc("BackupTaker", "Mutator", func(engine *Stepper) error {
+			hook := engine.GetHook().(*builderTestReconciler)
+			hook.states = append(hook.states, "backup taken in "+engine.GetState())
+			return nil
+		}).
+		HandleFunc("Updater", func(engine *Stepper) error { return errors.New("update failed") }).
+		AddStateFunc("HealthWaiter", "Updater", nil).
+		Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+
+	engine.Run()
+	require.Equal(t, []string{"CRChecker", "ObjectsGetter", "Mutator", "backup taken in BackupTaker", "ErrorManager"}, rec.states)
+	_, err = rec.ConsolidatedError()
+	require.EqualError(t, err, "update failed")
+}
+
+func TestStepperGraphExport(t *testing.T) {
+	rec := &builderTestReconciler{}
+	rec.Log = logr.Discard()
+	rec.Init("test", nil, nil)
+	engine, err := NewStepperBuilder().AddState("BackupTaker", "Mutator", nil).Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+	engine.Run()
+
+	mermaid := engine.GetGraph().ToMermaid(oktsm.LCGExportOptions{Path: engine.GetPathStates()})
+	require.Contains(t, mermaid, `s9["BackupTaker (x1)"]`)
+	require.Contains(t, mermaid, `s3 ==>|"3 (x1)"| s9`, "Mutator to BackupTaker is the default transition")
+	require.Contains(t, mermaid, `s5["ErrorManager"]`, "Not visited")
+}
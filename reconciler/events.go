@@ -0,0 +1,210 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	k8score "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// DefaultEventOperations are the operations recorded as events when no operation is selected (see EventsOptions)
+var DefaultEventOperations = []okterr.OperationResult{
+	okterr.OperationResultCreated,
+	okterr.OperationResultUpdated,
+	okterr.OperationResultDeleted,
+	okterr.OperationResultCRUDError,
+	okterr.OperationResultDriftDetected,
+	okterr.OperationResultSameStatusError,
+	okterr.OperationResultReconcileTimeout,
+}
+
+// Event reasons, by operation
+var eventReasons = map[okterr.OperationResult]string{
+	okterr.OperationResultCreated:            "Created",
+	okterr.OperationResultUpdated:            "Updated",
+	okterr.OperationResultDeleted:            "Deleted",
+	okterr.OperationResultDeleteInProgress:   "DeleteInProgress",
+	okterr.OperationResultDependencyNotReady: "DependencyNotReady",
+	okterr.OperationResultNotReady:           "NotReady",
+	okterr.OperationResultCRUDError:          "CRUDError",
+	okterr.OperationResultDriftDetected:      "DriftDetected",
+	okterr.OperationResultSameStatusError:    "SameStatusError",
+	okterr.OperationResultReconcileTimeout:   "ReconcileTimeout",
+	okterr.OperationResultStatusUpdateError:  "StatusUpdateError",
+
+	okterr.OperationResultCRFinalizationUpdateError:   "FinalizationUpdateError",
+	okterr.OperationResultCRFinalizerAddError:         "FinalizerAddError",
+	okterr.OperationResultCRFinalizationTaskError:     "FinalizationTaskError",
+	okterr.OperationResultCRFinalizationTaskTimeout:   "FinalizationTaskTimeout",
+	okterr.OperationResultCRFinalizationTaskAbandoned: "FinalizationTaskAbandoned",
+	okterr.OperationResultCRFinalized:                 "Finalized",
+}
+
+const (
+	eventReasonGiveUp  = "GiveUp"
+	eventReasonDefault = "Reconciliation"
+
+	defaultEventsDedupWindow = 10 * time.Minute
+)
+
+// EventsOptions defines which operations are recorded as Kubernetes events
+type EventsOptions struct {
+	// Operations to record, DefaultEventOperations if empty. Whatever this selection, a GiveUp with an alarming reason is always recorded.
+	Operations []okterr.OperationResult
+	// OnResources Record the events on the resources concerned by the operations too (not only on the CR)
+	OnResources bool
+	// DedupWindow An event identical to one already recorded during this period is not recorded again (10 minutes if 0)
+	DedupWindow time.Duration
+}
+
+// eventsRecorder records the operations as events and remembers the events recorded, to not flood the event stream.
+// It is shared by all the requests served by a reconciler.
+type eventsRecorder struct {
+	recorder    record.EventRecorder
+	operations  map[okterr.OperationResult]bool
+	onResources bool
+	dedupWindow time.Duration
+
+	lock     sync.Mutex
+	recorded map[string]time.Time // Last time an event was recorded, by event key
+}
+
+// resourceObject Return the object of an OKT resource, like the K8S resources (or their mutation helper) provide, nil if none
+func resourceObject(resource interface{}) runtime.Object {
+	switch res := resource.(type) {
+	case interface{ GetObject() client.Object }:
+		return res.GetObject()
+	case interface{ GetObject() runtime.Object }:
+		return res.GetObject()
+	}
+	return nil
+}
+
+func newEventsRecorder(recorder record.EventRecorder, opts EventsOptions) *eventsRecorder {
+	e := &eventsRecorder{
+		recorder:    recorder,
+		operations:  make(map[okterr.OperationResult]bool),
+		onResources: opts.OnResources,
+		dedupWindow: opts.DedupWindow,
+		recorded:    make(map[string]time.Time),
+	}
+	if e.dedupWindow == 0 {
+		e.dedupWindow = defaultEventsDedupWindow
+	}
+
+	operations := opts.Operations
+	if len(operations) == 0 {
+		operations = DefaultEventOperations
+	}
+	for _, op := range operations {
+		e.operations[op] = true
+	}
+
+	return e
+}
+
+// eventOf Return the event type, reason and message of an operation, or false if this operation is not recorded
+func (e *eventsRecorder) eventOf(op okterr.OpInfo) (eventType, reason, message string, selected bool) {
+	giveup, alarmingReason := false, op.Err
+	var giveupErr *okterr.ErrGiveUp
+	if errors.As(op.Err, &giveupErr) {
+		alarmingReason = giveupErr.AlarmingReasonToGiveup()
+		giveup = alarmingReason != nil
+	}
+	if !giveup && !e.operations[op.Operation] {
+		return "", "", "", false
+	}
+
+	eventType, reason = k8score.EventTypeNormal, eventReasons[op.Operation]
+	if reason == "" {
+		reason = eventReasonDefault
+	}
+	if giveup {
+		reason = eventReasonGiveUp
+	}
+
+	message = string(op.Operation)
+	if op.Resource != nil {
+		message = op.Resource.KindName() + ": " + message
+	}
+	if len(op.Details) > 0 {
+		message += " (" + strings.Join(op.Details, ", ") + ")"
+	}
+	if alarmingReason != nil {
+		eventType = k8score.EventTypeWarning
+		message += ": " + alarmingReason.Error()
+	}
+
+	return eventType, reason, message, true
+}
+
+// isDuplicate Tells if the same event has already been recorded on the object during the dedup window, else remember it
+func (e *eventsRecorder) isDuplicate(obj runtime.Object, eventType, reason, message string, now time.Time) bool {
+	key := eventType + "/" + reason + "/" + message
+	if metaObj, err := meta.Accessor(obj); err == nil {
+		key = string(metaObj.GetUID()) + "/" + metaObj.GetNamespace() + "/" + metaObj.GetName() + "/" + key
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if last, exists := e.recorded[key]; exists && now.Sub(last) < e.dedupWindow {
+		return true
+	}
+
+	// Forget the events out of the window
+	for k, last := range e.recorded {
+		if now.Sub(last) >= e.dedupWindow {
+			delete(e.recorded, k)
+		}
+	}
+	e.recorded[key] = now
+
+	return false
+}
+
+func (e *eventsRecorder) event(obj runtime.Object, eventType, reason, message string) {
+	if obj == nil || reflect.ValueOf(obj).Kind() == reflect.Ptr && reflect.ValueOf(obj).IsNil() || e.isDuplicate(obj, eventType, reason, message, time.Now()) {
+		return
+	}
+	e.recorder.Event(obj, eventType, reason, message)
+}
+
+// record Record the selected operations as events on the CR and on the resources (if enabled)
+func (e *eventsRecorder) record(cr runtime.Object, ops []okterr.OpInfo) {
+	for _, op := range ops {
+		eventType, reason, message, selected := e.eventOf(op)
+		if !selected {
+			continue
+		}
+
+		e.event(cr, eventType, reason, message)
+
+		if e.onResources {
+			e.event(resourceObject(op.Resource), eventType, reason, message)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okttools "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/k8sapi"
+)
+
+// requeueDurationOnFinalizationError Delay (seconds) before retrying a failed finalization task, if not set in its options
+const requeueDurationOnFinalizationError uint16 = 5
+
+// FinalizerOptions defines how a finalization task is retried
+type FinalizerOptions struct {
+	// Timeout Maximum duration of an attempt, provided to the task through its context. No timeout if 0.
+	Timeout time.Duration
+	// RetryAfter Delay (seconds) before the next attempt of a failed task, 5 seconds if 0
+	RetryAfter uint16
+	// GiveUpAfter Once this duration elapsed since the deletion of the CR, a failing task is abandoned and no longer prevents the
+	// CR finalizer to be removed. The task is retried as long as it fails if 0.
+	GiveUpAfter time.Duration
+}
+
+type finalizerTask struct {
+	Finalizer
+	FinalizerOptions
+}
+
+// finalizerInfo identifies a finalization task in the results
+type finalizerInfo struct {
+	name string
+}
+
+func (fi *finalizerInfo) Index() string {
+	return "Finalizer/" + fi.name
+}
+
+func (fi *finalizerInfo) KindName() string {
+	return "Finalizer/" + fi.name
+}
+
+// SetFinalizerName Set the finalizer added by the reconciler to the CR at its first reconciliation, none by default (see also RegisterFinalizer()).
+// The name must be domain-qualified, i.e. "example.com/cleanup" or OKTFinalizerName. An empty name disables the finalizer registration.
+func (r *BasicObject) SetFinalizerName(finalizer string) error {
+	if finalizer != "" {
+		if err := okttools.ValidateFinalizerName(finalizer); err != nil {
+			return err
+		}
+	}
+	r.finalizerName = finalizer
+	return nil
+}
+
+// GetFinalizerName Return the finalizer added by the reconciler to the CR, empty if disabled
+func (r *BasicObject) GetFinalizerName() string {
+	return r.finalizerName
+}
+
+// RegisterFinalizer Register a finalization task, i.e. the cleanup of external resources, run when the CR is being deleted.
+// The tasks are run in their registration order, once the engine's finalization (i.e. the Stepper's CRFinalizer state) completed
+// without error nor resource deletion in progress. The CR finalizer is removed only once all the tasks succeeded (or were abandoned).
+// The tasks are shared by all the requests served by the reconciler, register them at setup time.
+// If no finalizer is set (see SetFinalizerName()), the OKTFinalizerName finalizer is set as the tasks require one.
+func (r *BasicObject) RegisterFinalizer(task Finalizer, opts FinalizerOptions) {
+	if r.finalizerName == "" {
+		r.finalizerName = okttools.OKTFinalizerName
+	}
+	r.finalizers = append(r.finalizers, finalizerTask{Finalizer: task, FinalizerOptions: opts})
+}
+
+// hasCRFinalizer Tells if the CR has the finalizer of the reconciler, or the one named after the CR added by the former OKT versions
+func (r *BasicObject) hasCRFinalizer() bool {
+	return okttools.HasFinalizer(r.cr, r.finalizerName) || okttools.HasFinalizer(r.cr, r.controllerName)
+}
+
+// ensureCRFinalizer Add the finalizer of the reconciler to the CR and update it on the Cluster, unless the CR already has it or is being deleted
+func (r *BasicObject) ensureCRFinalizer() error {
+	if r.finalizerName == "" || okttools.IsBeingDeleted(r.cr) || okttools.HasFinalizer(r.cr, r.finalizerName) {
+		return nil
+	}
+
+	okttools.AddFinalizer(r.cr, r.finalizerName)
+	if err := r.Client.Update(r.GetContext(), r.cr); err != nil {
+		okttools.RemoveFinalizer(r.cr, r.finalizerName)
+		return r.Results.AddOp(&crInfo{cr: r.cr}, okterr.OperationResultCRFinalizerAddError, err, requeueDurationOnCRUDError)
+	}
+	r.snapshotCR()
+
+	r.Results.AddOpSuccess(&crInfo{cr: r.cr}, okterr.OperationResultCRFinalizerAdded)
+	return nil
+}
+
+// finalizeCR Run the finalization tasks then remove the CR finalizer, if the engine's finalization and all the tasks succeeded
+func (r *BasicObject) finalizeCR() {
+	if !r.isFinalizationCompleted() {
+		return
+	}
+
+	for _, task := range r.finalizers {
+		r.runFinalizer(task)
+	}
+
+	if r.isFinalizationCompleted() {
+		r.RemoveCRFinalizer()
+	}
+}
+
+// runFinalizer Run a finalization task and report its result. A failed task is retried after a requeue, unless its give-up delay elapsed.
+func (r *BasicObject) runFinalizer(task finalizerTask) {
+	info := &finalizerInfo{name: task.GetName()}
+
+	ctx := r.GetContext()
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+		defer cancel()
+	}
+
+	err := task.Finalize(ctx, r.cr)
+	if err == nil {
+		r.Results.AddOpSuccess(info, okterr.OperationResultCRFinalizationTaskDone)
+		return
+	}
+
+	if deletion := r.cr.GetDeletionTimestamp(); task.GiveUpAfter > 0 && deletion != nil && time.Since(deletion.Time) > task.GiveUpAfter {
+		r.Results.AddOpSuccessWithDetails(info, okterr.OperationResultCRFinalizationTaskAbandoned, []string{err.Error()})
+		return
+	}
+
+	result := okterr.OperationResultCRFinalizationTaskError
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result = okterr.OperationResultCRFinalizationTaskTimeout
+	}
+	retryAfter := task.RetryAfter
+	if retryAfter == 0 {
+		retryAfter = requeueDurationOnFinalizationError
+	}
+	r.Results.AddOp(info, result, err, retryAfter)
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resourceReadiness Tell if a registered resource is ready and, if not, the reason why.
+// A resource not yet created is not ready, a resource not implementing oktres.Readiness is ready as soon as it exists.
+func resourceReadiness(resource oktres.Resource) (bool, string) {
+	if resource.IsCreation() {
+		return false, "not created"
+	}
+	if readiness, ok := resource.(oktres.Readiness); ok {
+		return readiness.IsReady()
+	}
+	return true, ""
+}
+
+// WaitReady Check the readiness of all the registered resources (see oktres.Readiness), typically after their creation or update.
+// Each resource not ready is reported as OperationResultNotReady with a new reconciliation request delayed (by requeueDurationOnNotReady seconds),
+// which is not an error: the reconciliation goes on while the resources are progressing.
+// If the CR status conditions are managed, the readiness is reported in the Ready condition, updated with the status (see ManageSuccess()).
+// Return true if all the resources are ready.
+func (r *BasicObject) WaitReady() bool {
+	notReady := make([]string, 0)
+	for _, res := range r.GetRegisteredResources() {
+		if ready, reason := resourceReadiness(res); !ready {
+			notReady = append(notReady, res.KindName()+": "+reason)
+			r.AddOp(res, okterr.OperationResultNotReady, nil, requeueDurationOnNotReady)
+		}
+	}
+
+	if r.managedStatusConditions != nil {
+		cond := v1.Condition{Type: ReadyConditionType, Status: v1.ConditionTrue, Reason: "ResourcesReady"}
+		cond.Message = fmt.Sprint(len(r.GetRegisteredResources()), " resource(s) ready")
+		if len(notReady) > 0 {
+			cond.Status = v1.ConditionFalse
+			cond.Reason = "ResourcesNotReady"
+			cond.Message = strings.Join(notReady, ", ")
+		}
+		r.setCondition(cond)
+	}
+
+	return len(notReady) == 0
+}
@@ -15,6 +15,8 @@
 package reconciler
 
 import (
+	"context"
+
 	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
 	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
 	"github.com/go-logr/logr"
@@ -42,8 +44,12 @@ type Basic interface {
 	GetName() string
 
 	FetchCR(namespacedName types.NamespacedName) error
+	SpecChanged() bool
 	Create(resource oktres.Resource, maxCreation uint16) error
 	CreateAllResources(maxCreation uint16, stopOnError bool) error
+	Delete(resource oktres.Resource, propagation v1.DeletionPropagation, waitForGone bool) error
+	DeleteAllResources(propagation v1.DeletionPropagation, waitForGone, stopOnError bool) error
+	WaitReady() bool
 }
 
 // Advanced xx
@@ -52,6 +58,7 @@ type Advanced interface {
 	Mutate(resource oktres.MutableResourceType) error
 	Update(resource oktres.MutableResourceType) error
 	CreateOrUpdateAllResources(maxCreation uint16, stopOnError bool) error
+	PruneUnregisteredResources(dryRun, stopOnError bool) error
 
 	/*
 		GetAppStatus() (requeueAfterSeconds uint16, err error) // Can return a requeue without error when we are waiting that K8S res are up first
@@ -61,10 +68,40 @@ type Advanced interface {
 	*/
 }
 
+// Finalizer is a finalization task run when the CR is being deleted, i.e. the cleanup of resources out of the Cluster (see RegisterFinalizer())
+type Finalizer interface {
+	// GetName Return the name of the task, used to report its results
+	GetName() string
+	// Finalize Run the task for the CR being deleted, with a context carrying its timeout if any. Return an error to retry it at the
+	// next reconciliation. A task can be run again after its success (i.e. when another task failed), it must be idempotent.
+	Finalize(ctx context.Context, cr client.Object) error
+}
+
 // Engine reconciler engine
 type Engine interface {
 	SetLogger(logr.Logger)
 	SetResults(okterr.Results)
+	// SetContext provides the context of the request to serve (deadline, cancellation, logger)
+	SetContext(context.Context)
 
 	Run()
 }
+
+// ConcurrentEngine is an engine able to serve several reconciliation requests at the same time.
+// For each request, the reconciler (the hook) is copied and called by a copy of the engine with its own state.
+type ConcurrentEngine interface {
+	Engine
+
+	// GetHook Return the reconciler called by this engine
+	GetHook() interface{}
+	// Copy Return a new engine, with its own state, calling the hook provided (of the same type than the engine's hook)
+	Copy(hook interface{}) (Engine, error)
+}
+
+// MeasurableEngine is an engine reporting metrics of its own (i.e. the duration of its states) when the reconciler's metrics are enabled
+type MeasurableEngine interface {
+	Engine
+
+	// EnableMetrics Report the engine's metrics with the controller name provided as label
+	EnableMetrics(controller string)
+}
@@ -0,0 +1,194 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"fmt"
+	"reflect"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oktregistry "github.com/Orange-OpenSource/Operators-Karma-Tools/registry"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+var (
+	basicObjectType      = reflect.TypeOf(BasicObject{})
+	advancedObjectType   = reflect.TypeOf(AdvancedObject{})
+	statusConditionsType = reflect.TypeOf([]v1.Condition{})
+)
+
+// RequestHook is a reconciler (the engine's hook) having pointer, map, slice or channel fields, which are shared by the copies of
+// the reconciler serving the requests concurrently (see EnableConcurrentRequests()).
+type RequestHook interface {
+	// PrepareRequest Called on the copy of the reconciler serving a new request, before the request is served. Give the copy its own
+	// instance of the fields which must not be shared with the other requests. The fields left as is are shared and must be concurrency safe.
+	PrepareRequest()
+}
+
+// requestIsolation Locates the fields of the engine's hook to reset for each request: this BasicObject and the CR
+type requestIsolation struct {
+	engine    ConcurrentEngine
+	hook      reflect.Value
+	selfIndex []int
+	crIndex   []int
+}
+
+// EnableConcurrentRequests Serve each request with a copy of the reconciler (the engine's hook) with its own CR, registry, results and
+// engine state, thus the controller can run several workers (MaxConcurrentReconciles > 1). Call it after Init() and SetEngine().
+// Return an error if the requests can not be isolated: the engine is not a ConcurrentEngine, this reconciler or the CR provided at
+// Init() time (and its status conditions) are not fields of the engine's hook, or the hook has pointer, map, slice or channel fields
+// but does not implement RequestHook.
+// Without it, the requests are served one by one by the reconciler itself.
+func (r *BasicObject) EnableConcurrentRequests() error {
+	if _, err := r.requestIsolation(); err != nil {
+		return err
+	}
+	r.concurrentRequests = true
+	return nil
+}
+
+// requestIsolation Check that the requests can be served by copies of the engine's hook and locate the fields to reset for each request
+func (r *BasicObject) requestIsolation() (*requestIsolation, error) {
+	engine, ok := r.engine.(ConcurrentEngine)
+	if !ok {
+		return nil, fmt.Errorf("the engine %T can not serve concurrent requests, it is not a ConcurrentEngine", r.engine)
+	}
+
+	hook := reflect.ValueOf(engine.GetHook())
+	if hook.Kind() != reflect.Ptr || hook.IsNil() || hook.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("the hook %T of the engine is not a pointer on a struct", engine.GetHook())
+	}
+	iso := &requestIsolation{engine: engine, hook: hook}
+
+	var found bool
+	if iso.selfIndex, found = fieldIndex(hook.Elem(), reflect.ValueOf(r).Pointer(), basicObjectType); !found {
+		return nil, fmt.Errorf("the reconciler is not a field of the hook %T of the engine", engine.GetHook())
+	}
+	if r.cr == nil {
+		return nil, fmt.Errorf("no CR provided at Init() time")
+	}
+	if iso.crIndex, found = fieldIndex(hook.Elem(), reflect.ValueOf(r.cr).Pointer(), reflect.TypeOf(r.cr).Elem()); !found {
+		return nil, fmt.Errorf("the CR %T provided at Init() time is not a field (by value) of the hook %T", r.cr, engine.GetHook())
+	}
+	if r.managedStatusConditions != nil && r.statusConditionsIndex == nil {
+		return nil, fmt.Errorf("the status conditions provided at Init() time are not a field of the CR %T", r.cr)
+	}
+
+	if _, prepared := engine.GetHook().(RequestHook); !prepared {
+		if name, shared := sharedField(hook.Elem()); shared {
+			return nil, fmt.Errorf("the field %s of the hook %T would be shared by the requests, implement RequestHook", name, engine.GetHook())
+		}
+	}
+
+	return iso, nil
+}
+
+// sharedField Return the name of the first pointer, map, slice or channel field of the struct value (or of its embedded structs, except
+// the OKT reconcilers), which would be shared by the copies of the struct
+func sharedField(v reflect.Value) (string, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		switch field.Type.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan:
+			return field.Name, true
+		case reflect.Struct:
+			if !field.Anonymous || field.Type == basicObjectType || field.Type == advancedObjectType {
+				continue
+			}
+			if name, shared := sharedField(v.Field(i)); shared {
+				return field.Name + "." + name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// newRequest Return the context of a new reconciliation request: a copy of the reconciler (the engine's hook embedding this BasicObject)
+// with its own CR, registry, results and engine. Thus several requests can be served at the same time by the same reconciler.
+// The other fields of the hook are shallow copied, unless the hook implements RequestHook.
+func (r *BasicObject) newRequest() (*BasicObject, error) {
+	iso, err := r.requestIsolation()
+	if err != nil {
+		return nil, err
+	}
+
+	// Shallow copy of the reconciler, the settings (client, logger, params,...) are shared with the other requests
+	r.lock.Lock()
+	hookCopy := reflect.New(iso.hook.Elem().Type())
+	hookCopy.Elem().Set(iso.hook.Elem())
+	r.lock.Unlock()
+
+	engineCopy, err := iso.engine.Copy(hookCopy.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	req := hookCopy.Elem().FieldByIndex(iso.selfIndex).Addr().Interface().(*BasicObject)
+
+	// A CR of its own, with the same type information, fetched later from the Cluster
+	cr := hookCopy.Elem().FieldByIndex(iso.crIndex)
+	cr.Set(reflect.Zero(cr.Type()))
+	req.cr = cr.Addr().Interface().(client.Object)
+	req.cr.GetObjectKind().SetGroupVersionKind(r.cr.GetObjectKind().GroupVersionKind())
+	if r.managedStatusConditions != nil {
+		req.managedStatusConditions = cr.FieldByIndex(r.statusConditionsIndex).Addr().Interface().(*[]v1.Condition)
+	}
+
+	req.Results = okterr.NewResultList()
+	req.registry = oktregistry.New()
+	req.crIsFetched = false
+	req.crSnapshot = nil
+	req.conditionsSet = nil
+	req.controllerName = ""
+	req.CRHasToBeFinalized = false
+	req.SetEngine(engineCopy)
+
+	if hook, ok := hookCopy.Interface().(RequestHook); ok {
+		hook.PrepareRequest()
+	}
+
+	return req, nil
+}
+
+// done Keep the CR and the results of the request served by a copy of the reconciler, for inspection purpose only (i.e. tests)
+func (r *BasicObject) done(req *BasicObject) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	reflect.ValueOf(r.cr).Elem().Set(reflect.ValueOf(req.cr).Elem())
+	r.Results = req.Results
+}
+
+// fieldIndex Return the index sequence (see reflect.FieldByIndex()) of the settable field, of the struct value or of its nested structs,
+// which is at the address and of the type provided
+func fieldIndex(v reflect.Value, addr uintptr, typ reflect.Type) ([]int, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if field.Type() == typ && field.Addr().Pointer() == addr {
+			return []int{i}, true
+		}
+		if field.Kind() == reflect.Struct {
+			if index, found := fieldIndex(field, addr, typ); found {
+				return append([]int{i}, index...), true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package registry
+
+import (
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	oktngvk "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/ngvk"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// objectResource is a resource providing the K8S object it manages, like the OKT ResourceObject
+type objectResource interface {
+	GetObject() runtime.Object
+}
+
+// helpedResource is a resource providing its K8S object through its mutation helper, like the generated resource stubs
+type helpedResource interface {
+	GetObject() client.Object
+}
+
+// objectOf Return the K8S object managed by the resource, nil if unknown
+func objectOf(entry oktres.Resource) runtime.Object {
+	switch res := entry.(type) {
+	case objectResource:
+		return res.GetObject()
+	case helpedResource:
+		return res.GetObject()
+	}
+	return nil
+}
+
+// TypedReader provides the K8S objects of the registered resources, by kind and name
+type TypedReader interface {
+	GetObject(kind, name string) runtime.Object
+
+	GetConfigMap(name string) *corev1.ConfigMap
+	GetSecret(name string) *corev1.Secret
+	GetService(name string) *corev1.Service
+	GetServiceAccount(name string) *corev1.ServiceAccount
+	GetPod(name string) *corev1.Pod
+	GetPersistentVolumeClaim(name string) *corev1.PersistentVolumeClaim
+	GetDeployment(name string) *appsv1.Deployment
+	GetStatefulSet(name string) *appsv1.StatefulSet
+	GetDaemonSet(name string) *appsv1.DaemonSet
+	GetJob(name string) *batchv1.Job
+}
+
+// nameOf Return the name of a keyed resource, empty for the other resources
+func nameOf(entry oktres.Resource) string {
+	if key, ok := keyOf(entry); ok {
+		return key.NamespacedName().Name
+	}
+	return ""
+}
+
+// keyOf Return the NGVK key of a keyed resource, false for the other resources and for the keyed resources not initialized
+func keyOf(entry oktres.Resource) (oktngvk.NGVK, bool) {
+	keyed, ok := entry.(oktres.KeyedResource)
+	if !ok {
+		return oktngvk.NGVK{}, false
+	}
+	key := keyed.GetNGVK()
+	return key, key != oktngvk.NGVK{}
+}
+
+// GetEntryByNGVK Retrieve entry from its NGVK key (NamespacedName Group Version Kind)
+func (reg *Registry) GetEntryByNGVK(key oktngvk.NGVK) oktres.Resource {
+	return reg.byKey[key.String()]
+}
+
+// GetEntriesByKind Retrieve the entries of a kind (i.e. "StatefulSet"), in registration order
+func (reg *Registry) GetEntriesByKind(kind string) []oktres.Resource {
+	return reg.byKind[kind]
+}
+
+// GetEntriesByName Retrieve the entries with the name provided, whatever their kind and namespace, in registration order
+func (reg *Registry) GetEntriesByName(name string) []oktres.Resource {
+	entries := make([]oktres.Resource, 0)
+	for _, entry := range reg.elems {
+		if nameOf(entry) == name {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// GetEntriesByLabels Retrieve the entries whose object's labels match the selector, in registration order
+func (reg *Registry) GetEntriesByLabels(selector labels.Selector) []oktres.Resource {
+	entries := make([]oktres.Resource, 0)
+	for _, entry := range reg.elems {
+		obj := objectOf(entry)
+		if obj == nil {
+			continue
+		}
+		metaObj, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(metaObj.GetLabels())) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// GetObject Return the K8S object of the first entry registered with the kind and name provided, nil if none.
+// Use a type assertion to get the object of your own type, see the typed getters (GetStatefulSet(),...) for the common kinds.
+func (reg *Registry) GetObject(kind, name string) runtime.Object {
+	for _, entry := range reg.byKind[kind] {
+		if nameOf(entry) == name {
+			return objectOf(entry)
+		}
+	}
+	return nil
+}
+
+// GetConfigMap Return the ConfigMap registered with the name provided, nil if none
+func (reg *Registry) GetConfigMap(name string) *corev1.ConfigMap {
+	obj, _ := reg.GetObject("ConfigMap", name).(*corev1.ConfigMap)
+	return obj
+}
+
+// GetSecret Return the Secret registered with the name provided, nil if none
+func (reg *Registry) GetSecret(name string) *corev1.Secret {
+	obj, _ := reg.GetObject("Secret", name).(*corev1.Secret)
+	return obj
+}
+
+// GetService Return the Service registered with the name provided, nil if none
+func (reg *Registry) GetService(name string) *corev1.Service {
+	obj, _ := reg.GetObject("Service", name).(*corev1.Service)
+	return obj
+}
+
+// GetServiceAccount Return the ServiceAccount registered with the name provided, nil if none
+func (reg *Registry) GetServiceAccount(name string) *corev1.ServiceAccount {
+	obj, _ := reg.GetObject("ServiceAccount", name).(*corev1.ServiceAccount)
+	return obj
+}
+
+// GetPod Return the Pod registered with the name provided, nil if none
+func (reg *Registry) GetPod(name string) *corev1.Pod {
+	obj, _ := reg.GetObject("Pod", name).(*corev1.Pod)
+	return obj
+}
+
+// GetPersistentVolumeClaim Return the PersistentVolumeClaim registered with the name provided, nil if none
+func (reg *Registry) GetPersistentVolumeClaim(name string) *corev1.PersistentVolumeClaim {
+	obj, _ := reg.GetObject("PersistentVolumeClaim", name).(*corev1.PersistentVolumeClaim)
+	return obj
+}
+
+// GetDeployment Return the Deployment registered with the name provided, nil if none
+func (reg *Registry) GetDeployment(name string) *appsv1.Deployment {
+	obj, _ := reg.GetObject("Deployment", name).(*appsv1.Deployment)
+	return obj
+}
+
+// GetStatefulSet Return the StatefulSet registered with the name provided, nil if none
+func (reg *Registry) GetStatefulSet(name string) *appsv1.StatefulSet {
+	obj, _ := reg.GetObject("StatefulSet", name).(*appsv1.StatefulSet)
+	return obj
+}
+
+// GetDaemonSet Return the DaemonSet registered with the name provided, nil if none
+func (reg *Registry) GetDaemonSet(name string) *appsv1.DaemonSet {
+	obj, _ := reg.GetObject("DaemonSet", name).(*appsv1.DaemonSet)
+	return obj
+}
+
+// GetJob Return the Job registered with the name provided, nil if none
+func (reg *Registry) GetJob(name string) *batchv1.Job {
+	obj, _ := reg.GetObject("Job", name).(*batchv1.Job)
+	return obj
+}
@@ -15,35 +15,40 @@
 package registry
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	oktngvk "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/ngvk"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // Reader provide read only access to a registry
 type Reader interface {
 	GetEntry(index string) oktres.Resource
 	Entries() []oktres.Resource
+
+	GetEntryByNGVK(key oktngvk.NGVK) oktres.Resource
+	GetEntriesByKind(kind string) []oktres.Resource
+	GetEntriesByName(name string) []oktres.Resource
+	GetEntriesByLabels(selector labels.Selector) []oktres.Resource
+	TypedReader
 }
 
-// Registry manages a list of objects to reconcile
+// Registry manages a list of objects to reconcile, indexed by their index key and their kind
 type Registry struct {
-	elems []oktres.Resource
+	elems  []oktres.Resource            // In registration order
+	byKey  map[string]oktres.Resource   // By index
+	byKind map[string][]oktres.Resource // By kind, for the keyed resources only (see oktres.KeyedResource)
 }
 
 // blank assignment to check at compilation time this type implementation
 var _ Reader = &Registry{}
 
-func getEntry(elems []oktres.Resource, index string) oktres.Resource {
-	for _, entry := range elems {
-		if entry.Index() == index {
-			return entry
-		}
-	}
-	return nil
-}
-
 // GetEntry Retrieve entry from its index key
 func (reg *Registry) GetEntry(index string) oktres.Resource {
-	return getEntry(reg.elems, index)
+	return reg.byKey[index]
 }
 
 // Entries is the slice of all registry elements
@@ -51,16 +56,109 @@ func (reg *Registry) Entries() []oktres.Resource {
 	return reg.elems
 }
 
+// dependencies Return the indexes of the resources the entry depends on, if any
+func dependencies(entry oktres.Resource) []string {
+	if dependent, ok := entry.(oktres.DependentResource); ok {
+		return dependent.Dependencies()
+	}
+	return nil
+}
+
+// SortedEntries is the slice of all registry elements in a dependency order: each entry comes after the entries it depends on
+// (see oktres.DependentResource). Entries without dependency between them remain in the registration order.
+// Return an error if an entry depends on an unregistered index or if dependencies form a cycle.
+func (reg *Registry) SortedEntries() ([]oktres.Resource, error) {
+	for _, entry := range reg.elems {
+		for _, index := range dependencies(entry) {
+			if reg.GetEntry(index) == nil {
+				return nil, fmt.Errorf("%s depends on an unregistered resource: %s", entry.KindName(), index)
+			}
+		}
+	}
+
+	sorted := make([]oktres.Resource, 0, len(reg.elems))
+	done := make(map[string]bool, len(reg.elems))
+
+	isFree := func(entry oktres.Resource) bool {
+		for _, index := range dependencies(entry) {
+			if !done[index] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Take the first entry, in registration order, whose dependencies are all sorted
+	for len(sorted) < len(reg.elems) {
+		var next oktres.Resource
+		for _, entry := range reg.elems {
+			if !done[entry.Index()] && isFree(entry) {
+				next = entry
+				break
+			}
+		}
+		if next == nil {
+			return nil, reg.cycleError(done)
+		}
+		done[next.Index()] = true
+		sorted = append(sorted, next)
+	}
+
+	return sorted, nil
+}
+
+// cycleError Return the error describing a cycle among the entries not sorted: each of them depends on another one not sorted
+func (reg *Registry) cycleError(done map[string]bool) error {
+	var entry oktres.Resource
+	for _, e := range reg.elems {
+		if !done[e.Index()] {
+			entry = e
+			break
+		}
+	}
+
+	// Follow the dependencies not sorted until an entry is visited twice
+	path := make([]oktres.Resource, 0)
+	visited := make(map[string]int)
+	for {
+		if at, ok := visited[entry.Index()]; ok {
+			path = append(path[at:], entry)
+			break
+		}
+		visited[entry.Index()] = len(path)
+		path = append(path, entry)
+		for _, index := range dependencies(entry) {
+			if !done[index] {
+				entry = reg.GetEntry(index)
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(path))
+	for _, e := range path {
+		names = append(names, e.KindName())
+	}
+	return fmt.Errorf("dependency cycle between resources: %s", strings.Join(names, " -> "))
+}
+
 // MutableEntries is the slice of all registry elements
 
 // Reset Delete all registry elements and re-init the registry to 0 element
 func (reg *Registry) Reset() {
-	reg.elems = nil
 	reg.elems = make([]oktres.Resource, 0)
+	reg.byKey = make(map[string]oktres.Resource)
+	reg.byKind = make(map[string][]oktres.Resource)
 }
 
 func (reg *Registry) addEntry(entry oktres.Resource) error {
 	reg.elems = append(reg.elems, entry)
+	reg.byKey[entry.Index()] = entry
+
+	if key, ok := keyOf(entry); ok {
+		kind := key.GVK().Kind
+		reg.byKind[kind] = append(reg.byKind[kind], entry)
+	}
 
 	return nil
 }
@@ -68,12 +166,21 @@ func (reg *Registry) addEntry(entry oktres.Resource) error {
 // AddEntry Register a resource (Mutable or Not) in a registry if it doesn't already exsist.
 // Else return an error
 func (reg *Registry) AddEntry(entry oktres.Resource) error {
+	index := entry.Index()
+	if index == "" {
+		return errors.New("resource not initialized, no index to register it")
+	}
+	if _, exists := reg.byKey[index]; exists {
+		return errors.New("resource already registered: " + entry.KindName())
+	}
+
 	return reg.addEntry(entry)
 }
 
 // New Allocates a new registry
 func New() *Registry {
-	elems := make([]oktres.Resource, 0)
+	reg := &Registry{}
+	reg.Reset()
 
-	return &Registry{elems: elems}
+	return reg
 }
@@ -22,7 +22,13 @@ import (
 	//	corev1 "k8s.io/api/core/v1"
 	//	meta "k8s.io/apimachinery/pkg/api/meta"
 	//	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
 	oktk8s "github.com/Orange-OpenSource/Operators-Karma-Tools/resources/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestRegistryNew(t *testing.T) {
@@ -38,3 +44,124 @@ func TestRegistryNew(t *testing.T) {
 
 	//registry.AddEntry()
 }
+
+// stubResource is a registry entry depending on other entries
+type stubResource struct {
+	oktk8s.ResourceObject
+	index string
+}
+
+func (r *stubResource) Index() string    { return r.index }
+func (r *stubResource) KindName() string { return "Stub/" + r.index }
+
+func newStub(index string, dependencies ...string) *stubResource {
+	r := &stubResource{index: index}
+	r.DependsOn(dependencies...)
+	return r
+}
+
+func sortedIndexes(t *testing.T, registry *Registry) []string {
+	entries, err := registry.SortedEntries()
+	require.NoError(t, err)
+
+	indexes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		indexes = append(indexes, entry.Index())
+	}
+	return indexes
+}
+
+func TestRegistrySortedEntries(t *testing.T) {
+	registry := New()
+	registry.AddEntry(newStub("deploy", "cm", "secret"))
+	registry.AddEntry(newStub("svc"))
+	registry.AddEntry(newStub("secret"))
+	registry.AddEntry(newStub("cm", "secret"))
+
+	require.Equal(t, []string{"svc", "secret", "cm", "deploy"}, sortedIndexes(t, registry))
+	require.Equal(t, "deploy", registry.Entries()[0].Index(), "The registration order is unchanged")
+
+	registry.Reset()
+	registry.AddEntry(newStub("a"))
+	registry.AddEntry(newStub("b"))
+	require.Equal(t, []string{"a", "b"}, sortedIndexes(t, registry), "Registration order without dependencies")
+}
+
+func TestRegistrySortedEntriesErrors(t *testing.T) {
+	registry := New()
+	registry.AddEntry(newStub("a", "unknown"))
+	_, err := registry.SortedEntries()
+	require.EqualError(t, err, "Stub/a depends on an unregistered resource: unknown")
+
+	registry.Reset()
+	registry.AddEntry(newStub("free"))
+	registry.AddEntry(newStub("dependent", "a"))
+	registry.AddEntry(newStub("a", "b"))
+	registry.AddEntry(newStub("b", "c"))
+	registry.AddEntry(newStub("c", "a"))
+	_, err = registry.SortedEntries()
+	require.EqualError(t, err, "dependency cycle between resources: Stub/a -> Stub/b -> Stub/c -> Stub/a")
+}
+
+// newEntry Return a resource of the kind provided (StatefulSet, Secret or ConfigMap) with the labels provided
+func newEntry(t *testing.T, kind, name string, lbls map[string]string) *oktk8s.ResourceObject {
+	var obj k8sclient.Object
+	switch kind {
+	case "StatefulSet":
+		obj = &appsv1.StatefulSet{TypeMeta: metav1.TypeMeta{Kind: kind, APIVersion: "apps/v1"}}
+	case "Secret":
+		obj = &corev1.Secret{TypeMeta: metav1.TypeMeta{Kind: kind, APIVersion: "v1"}}
+	default:
+		obj = &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{Kind: kind, APIVersion: "v1"}}
+	}
+	obj.SetLabels(lbls)
+
+	res := &oktk8s.ResourceObject{}
+	require.NoError(t, res.Init(nil, obj, "ns", name))
+	return res
+}
+
+func TestRegistryAddEntryDuplicate(t *testing.T) {
+	registry := New()
+	require.NoError(t, registry.AddEntry(newEntry(t, "ConfigMap", "a", nil)))
+	require.EqualError(t, registry.AddEntry(newEntry(t, "ConfigMap", "a", nil)), "resource already registered: ConfigMap/a")
+	require.NoError(t, registry.AddEntry(newEntry(t, "Secret", "a", nil)), "Same name, another kind")
+	require.Error(t, registry.AddEntry(&oktk8s.ResourceObject{}), "Not initialized")
+	require.Len(t, registry.Entries(), 2)
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry := New()
+	web := newEntry(t, "StatefulSet", "web", map[string]string{"tier": "front"})
+	db := newEntry(t, "StatefulSet", "db", map[string]string{"tier": "back"})
+	conf := newEntry(t, "ConfigMap", "web", map[string]string{"tier": "front"})
+	for _, res := range []*oktk8s.ResourceObject{web, db, conf} {
+		require.NoError(t, registry.AddEntry(res))
+	}
+
+	require.Equal(t, db, registry.GetEntry(db.Index()))
+	require.Equal(t, db, registry.GetEntryByNGVK(db.GetNGVK()))
+	require.Nil(t, registry.GetEntry("unknown"))
+
+	require.Equal(t, []oktres.Resource{web, db}, registry.GetEntriesByKind("StatefulSet"))
+	require.Empty(t, registry.GetEntriesByKind("Deployment"))
+	require.Equal(t, []oktres.Resource{web, conf}, registry.GetEntriesByName("web"))
+
+	selector := labels.SelectorFromSet(labels.Set{"tier": "front"})
+	require.Equal(t, []oktres.Resource{web, conf}, registry.GetEntriesByLabels(selector))
+
+	sts := registry.GetStatefulSet("db")
+	require.NotNil(t, sts)
+	require.Equal(t, "back", sts.Labels["tier"])
+	require.NotNil(t, registry.GetConfigMap("web"))
+	require.Nil(t, registry.GetStatefulSet("unknown"))
+	require.Nil(t, registry.GetDeployment("web"))
+}
+
+func TestRegistryNotKeyed(t *testing.T) {
+	registry := New()
+	stub := newStub("stub")
+	require.NoError(t, registry.AddEntry(stub), "A resource without NGVK key is registered by its index")
+	require.Equal(t, stub, registry.GetEntry("stub"))
+	require.Empty(t, registry.GetEntriesByKind(""))
+}
@@ -15,8 +15,11 @@
 package k8s
 
 import (
+	"context"
 	"errors"
 
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	oktdiff "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/diff"
 	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
 	//"k8s.io/kubernetes/pkg/apis/apps
 )
@@ -27,13 +30,31 @@ type MutableResourceObject struct {
 
 	needResync    bool // false by default
 	lastSyncState bool // false by default
+	drifted       bool // false by default
+	legacyPeer    bool // The peer has been written by a former version, without the hash of the expected object as last applied
+
+	acceptedHash string // Fingerprint of the drifted peer accepted, to store if the peer is not updated
+
+	peerSnapshot *oktdiff.Snapshot // Hashable fields of the peer before mutation
+
+	// How to treat an out-of-band modification of the peer. Revert by default.
+	DriftPolicy oktres.DriftPolicy
+
+	// True if the mutations only depend on the CR spec: they can then be skipped while the spec is unchanged (see SpecAwareResource)
+	SpecOnlyMutation bool
+	specChanged      bool
 }
 
 // Blank assignement to check type
-//var _ oktres.MutableResource = &MutableResourceObject{}
+// var _ oktres.MutableResource = &MutableResourceObject{}
+var _ oktres.DriftDetector = &MutableResourceObject{}
+var _ oktres.Differ = &MutableResourceObject{}
+var _ oktres.SpecAwareResource = &MutableResourceObject{}
 
 // UpdateSyncStatus Apply an annotation fingerprint to the object which permits to follows object modifications.
 // Update status telling if yes or no the expected value is Synched with the Cluster Resource
+// The fingerprint of the expected object as last applied is the reference, if it has been stored (see RecordPeer()), otherwise
+// the one of the peer.
 // When it is an object creation, no existing hash value exists, thus the needResync property will be TRUE!
 func (r *MutableResourceObject) UpdateSyncStatus(ref okthash.HashableRef) error {
 	var err error
@@ -41,10 +62,19 @@ func (r *MutableResourceObject) UpdateSyncStatus(ref okthash.HashableRef) error
 	//obj := r.GetExpected()
 	obj := r.Object
 
+	applied, recorded := obj.GetAnnotations()[okthash.OKTAppliedHashAnnotationName]
 	if r.lastSyncState, err = okthash.GenerateNew(obj, ref.GetRef()); err != nil {
 		return err
 	}
 
+	annotations := obj.GetAnnotations()
+	hash := okthash.GetTemplateHashAnnotation(annotations)
+	if recorded {
+		r.lastSyncState = hash != applied
+	}
+	annotations[okthash.OKTAppliedHashAnnotationName] = hash
+	obj.SetAnnotations(annotations)
+
 	// Update Synch status can be called twice, so don't set to false if it is already true!
 	if r.lastSyncState {
 		r.needResync = true
@@ -64,15 +94,168 @@ func (r *MutableResourceObject) LastSyncState() bool {
 	return r.lastSyncState
 }
 
+// DetectDrift Compare the hashable fields of the object with the fingerprint (hash) stored in its annotations at the last update.
+// It must be called on the peer object as picked up from the Cluster, before any mutation.
+// With the DriftPolicyRevert policy, a drift makes the object to resync.
+// A peer written by a former version (fingerprint without the one of the expected object as last applied) is not checked: its
+// fingerprint was the one of the expected object, not of the peer. It is recorded instead (see IsLegacyPeer()).
+func (r *MutableResourceObject) DetectDrift(ref okthash.HashableRef) (bool, error) {
+	var err error
+
+	r.drifted, r.legacyPeer = false, false
+	if r.IsCreation() {
+		return false, nil
+	}
+
+	annotations := r.Object.GetAnnotations()
+	if _, applied := annotations[okthash.OKTAppliedHashAnnotationName]; !applied {
+		_, r.legacyPeer = annotations[okthash.OKTHashAnnotationName]
+		if r.legacyPeer {
+			return false, nil
+		}
+	}
+
+	if r.drifted, err = okthash.HasDrifted(r.Object, ref.GetRef()); err != nil {
+		return false, err
+	}
+	if r.drifted && r.GetDriftPolicy() == oktres.DriftPolicyRevert {
+		r.needResync = true
+	}
+	return r.drifted, nil
+}
+
+// IsDrifted Tells if the last call to DetectDrift() detected an out-of-band modification of the peer
+func (r *MutableResourceObject) IsDrifted() bool {
+	return r.drifted
+}
+
+// IsLegacyPeer Tells if the last call to DetectDrift() found a peer written by a former version, to record (see RecordPeer())
+func (r *MutableResourceObject) IsLegacyPeer() bool {
+	return r.legacyPeer
+}
+
+// AcceptDrift Store the fingerprint (hash) of the object as is, without changing the sync status.
+// The modifications made out of OKT are then no longer detected as a modification to resync, nor as a drift once the fingerprint
+// is stored on the peer by its next update or by SaveAcceptedDrift().
+func (r *MutableResourceObject) AcceptDrift(ref okthash.HashableRef) error {
+	if _, err := okthash.GenerateNew(r.Object, ref.GetRef()); err != nil {
+		return err
+	}
+	r.acceptedHash = okthash.GetTemplateHashAnnotation(r.Object.GetAnnotations())
+	return nil
+}
+
+// RecordPeer Store the fingerprint of the peer as returned by the Cluster, with the fields it has defaulted, as the reference to detect
+// a drift and as the expected object last applied. Must be called right after the creation or the update of the peer.
+// In server-side apply mode, the expected object last applied is the one applied, made only of the fields set by the mutations,
+// so only the reference to detect a drift is stored.
+// The annotations of the peer are patched only if the fingerprints differ from the ones written.
+// A legacy peer (see IsLegacyPeer()), not updated, is recorded with both fingerprints, computed on the object mutated in sync with it.
+func (r *MutableResourceObject) RecordPeer(ctx context.Context, ref okthash.HashableRef) error {
+	r.acceptedHash = ""
+	legacy := r.legacyPeer
+	r.legacyPeer = false
+
+	hash, err := okthash.ComputeObject(r.Object, ref.GetRef())
+	if err != nil {
+		return err
+	}
+
+	names := []string{okthash.OKTHashAnnotationName}
+	if !r.IsServerSideApplyEnabled() || legacy {
+		names = append(names, okthash.OKTAppliedHashAnnotationName)
+	}
+
+	annotations := r.Object.GetAnnotations()
+	hashes := map[string]string{}
+	for _, name := range names {
+		if annotations[name] != hash || legacy {
+			hashes[name] = hash
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	return r.PatchAnnotations(ctx, hashes)
+}
+
+// SaveAcceptedDrift Store on the peer the fingerprint accepted by AcceptDrift(), if any, thus the drift is no longer detected.
+// Must be called instead of RecordPeer() when the peer is not updated.
+func (r *MutableResourceObject) SaveAcceptedDrift(ctx context.Context) error {
+	if r.acceptedHash == "" {
+		return nil
+	}
+	if err := r.PatchAnnotations(ctx, map[string]string{okthash.OKTHashAnnotationName: r.acceptedHash}); err != nil {
+		return err
+	}
+	r.acceptedHash = ""
+	return nil
+}
+
+// GetDriftPolicy Return the drift policy of this resource (DriftPolicyRevert if not set)
+func (r *MutableResourceObject) GetDriftPolicy() oktres.DriftPolicy {
+	if r.DriftPolicy == "" {
+		return oktres.DriftPolicyRevert
+	}
+	return r.DriftPolicy
+}
+
+// SetSpecChanged Set whether the CR spec changed since the last successful reconciliation
+func (r *MutableResourceObject) SetSpecChanged(changed bool) {
+	r.specChanged = changed
+}
+
+// SpecChanged Tells if the CR spec changed since the last successful reconciliation, i.e. to mutate only what depends on the spec
+func (r *MutableResourceObject) SpecChanged() bool {
+	return r.specChanged
+}
+
+// IsSpecOnlyMutation Tells if the mutations of this resource only depend on the CR spec (see SpecOnlyMutation)
+func (r *MutableResourceObject) IsSpecOnlyMutation() bool {
+	return r.SpecOnlyMutation
+}
+
+// SnapshotPeer Keep a copy of the hashable fields of the object. Must be called on the peer object, before any mutation.
+func (r *MutableResourceObject) SnapshotPeer(ref okthash.HashableRef) error {
+	var err error
+	r.peerSnapshot, err = oktdiff.NewSnapshot(r.Object, ref.GetRef())
+	return err
+}
+
+// DiffFromPeer Return the changes of the hashable fields since the peer snapshot. The values of a Secret are redacted.
+// In server-side apply mode, the fields not set by the mutations are not reported as removed.
+// Return no change if no snapshot has been taken (i.e. a creation)
+func (r *MutableResourceObject) DiffFromPeer(ref okthash.HashableRef) ([]string, error) {
+	if r.peerSnapshot == nil {
+		return nil, nil
+	}
+
+	expected, err := oktdiff.NewSnapshot(r.Object, ref.GetRef())
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]string, 0)
+	for _, change := range r.peerSnapshot.Diff(expected) {
+		// In server-side apply mode, a field not set by the mutations is left as is, not removed
+		if r.IsServerSideApplyEnabled() && change.To == nil {
+			continue
+		}
+		changes = append(changes, change.String())
+	}
+	return changes, nil
+}
+
 // UpdatePeer Update peer object. Can NOT succeed if the resource has been marked as "to be created"
 // at OKT's registration time.
+// In server-side apply mode, the peer is applied instead of being updated.
 // Reset NeedResync flag to false in case of success.
-func (r *MutableResourceObject) UpdatePeer() error {
+func (r *MutableResourceObject) UpdatePeer(ctx context.Context) error {
 	if r.IsCreation() {
 		return errors.New("Peer is presumed not yet created:" + r.Index())
 	}
 
-	if err := r.Update(); err != nil {
+	if err := r.updateOrApply(ctx); err != nil {
 		return err
 	}
 	r.needResync = false
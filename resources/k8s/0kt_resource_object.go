@@ -16,12 +16,15 @@ package k8s
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 
 	oktclients "github.com/Orange-OpenSource/Operators-Karma-Tools/clients"
 	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
 	oktngvk "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/ngvk"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -52,11 +55,21 @@ type ResourceObject struct {
 
 	createObj bool // The object is not yet created
 
+	// Server-side apply options, nil when this mode is disabled
+	serverSideApply *oktclients.ApplyOptions
+
 	params map[string]string
+
+	// Indexes of the resources to create before this one
+	dependencies []string
 }
 
 // Blank assignement to check type
 var _ oktres.Resource = &ResourceObject{}
+var _ oktres.ServerSideApplyResource = &ResourceObject{}
+var _ oktres.DependentResource = &ResourceObject{}
+var _ oktres.KeyedResource = &ResourceObject{}
+var _ oktres.Readiness = &ResourceObject{}
 
 // Init Initialize this resource with its Client (K8S) and a runtime object for the Namespace and Name provided
 func (or *ResourceObject) Init(client k8sclient.Client, objtyp k8sclient.Object, namespace, name string) error {
@@ -114,7 +127,8 @@ func (or *ResourceObject) CopyTpl(yaml string, tplValues interface{}) error {
 
 // ApplyGOStruct is a way to copy a source object into a destination object different than the src.DeepCopyInto(dst).
 // It Apply the src GO data structure to a resource object preserving all other existing fields in the destination not present in the source struct.
-//  Here dst object is the resource to update. and the src is the GO struct provided for the update.
+//
+//	Here dst object is the resource to update. and the src is the GO struct provided for the update.
 func (or *ResourceObject) CopyGOStruct(src interface{}) error {
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
@@ -166,11 +180,23 @@ func (or *ResourceObject) setIndex() error {
 	return err
 }
 
-// Index Return entry a key index (NGVK, aka GroupVersion Kind and Name) string
+// Index Return entry a key index (NGVK, aka GroupVersion Kind and Name) string, empty if the resource is not initialized
 func (or *ResourceObject) Index() string {
+	if or.key == nil {
+		return ""
+	}
 	return or.key.String()
 }
 
+// GetNGVK Return the NGVK key (NamespacedName Group Version Kind) computed at Init() time for this resource, zero if the resource is
+// not initialized
+func (or *ResourceObject) GetNGVK() oktngvk.NGVK {
+	if or.key == nil {
+		return oktngvk.NGVK{}
+	}
+	return *or.key
+}
+
 // KindName Return Kind/Name string for this resource
 func (or *ResourceObject) KindName() string {
 	return or.key.KN()
@@ -178,17 +204,17 @@ func (or *ResourceObject) KindName() string {
 
 // SyncFromPeer Try to get peer object which determines if it is a creation or not
 // The caller (typically the Reconciler) is in 3 possibles states regarding the resource:
-//     - It dont know if the resource exists on the cluster
-//     - It has already got an existing resource on the cluster and need a refresh
-//     - It is already informed that the resource doest not exists but ask again => NOTHING WILL BE DONE HERE
-func (or *ResourceObject) SyncFromPeer() error {
+//   - It dont know if the resource exists on the cluster
+//   - It has already got an existing resource on the cluster and need a refresh
+//   - It is already informed that the resource doest not exists but ask again => NOTHING WILL BE DONE HERE
+func (or *ResourceObject) SyncFromPeer(ctx context.Context) error {
 	// Already done and a creation is required first ?
 	if or.createObj {
 		return nil
 	}
 
 	// Get peer
-	if err := or.Get(); err != nil {
+	if err := or.Get(ctx); err != nil {
 		if !k8serrors.IsNotFound(err) {
 			return err
 		}
@@ -207,13 +233,99 @@ func (or *ResourceObject) IsCreation() bool {
 	return or.createObj
 }
 
+// DependsOn Add the indexes of the resources which must exist, and be ready, before the creation of this one
+func (or *ResourceObject) DependsOn(indexes ...string) {
+	or.dependencies = append(or.dependencies, indexes...)
+}
+
+// Dependencies Return the indexes of the resources this one depends on
+func (or *ResourceObject) Dependencies() []string {
+	return or.dependencies
+}
+
+// IsReady Tell if the peer, as read on the Cluster, is ready (see ObjectReadiness()). A peer not yet created is not ready.
+func (or *ResourceObject) IsReady() (bool, string) {
+	if or.createObj {
+		return false, "not created"
+	}
+	return ObjectReadiness(or.Object)
+}
+
+// EnableServerSideApply Create and update the peer with the server-side apply method, as the field manager provided, instead of plain Create/Update requests.
+// With forceConflicts, the fields already owned by another manager are taken over instead of failing on conflict.
+func (or *ResourceObject) EnableServerSideApply(fieldManager string, forceConflicts bool) {
+	or.serverSideApply = &oktclients.ApplyOptions{FieldManager: fieldManager, ForceConflicts: forceConflicts}
+}
+
+// IsServerSideApplyEnabled Tells if the server-side apply mode is enabled for this resource
+func (or *ResourceObject) IsServerSideApplyEnabled() bool {
+	return or.serverSideApply != nil
+}
+
+// PrepareApply Reset the object, as picked up from the Cluster, to its identity (GVK, namespace and name) and its OKT fingerprints.
+// Must be called before the mutations of an existing peer in server-side apply mode: the apply configuration is then made only of
+// the fields set by the mutations, thus the fields defaulted by the Cluster or set by other managers are neither applied nor owned.
+func (or *ResourceObject) PrepareApply() error {
+	if or.serverSideApply == nil || or.createObj {
+		return nil
+	}
+
+	annotations := map[string]string{}
+	for name, value := range or.Object.GetAnnotations() {
+		if name == okthash.OKTHashAnnotationName || name == okthash.OKTAppliedHashAnnotationName {
+			annotations[name] = value
+		}
+	}
+
+	value := reflect.ValueOf(or.Object)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("the object of %s must be a pointer", or.Index())
+	}
+	value.Elem().Set(reflect.Zero(value.Elem().Type()))
+
+	or.Object.GetObjectKind().SetGroupVersionKind(or.key.GVK())
+	or.Object.SetNamespace(or.key.NamespacedName().Namespace)
+	or.Object.SetName(or.key.NamespacedName().Name)
+	if len(annotations) > 0 {
+		or.Object.SetAnnotations(annotations)
+	}
+	return nil
+}
+
+// apply Applies the peer in server-side apply mode. The fingerprint of the peer is not applied, it is stored after the write
+// (see MutableResourceObject.RecordPeer()).
+func (or *ResourceObject) apply(ctx context.Context) error {
+	annotations := or.Object.GetAnnotations()
+	if _, exists := annotations[okthash.OKTHashAnnotationName]; exists {
+		delete(annotations, okthash.OKTHashAnnotationName)
+		or.Object.SetAnnotations(annotations)
+	}
+	return or.Apply(ctx, *or.serverSideApply)
+}
+
+// createOrApply Creates the peer or applies it in server-side apply mode
+func (or *ResourceObject) createOrApply(ctx context.Context) error {
+	if or.serverSideApply != nil {
+		return or.apply(ctx)
+	}
+	return or.Create(ctx)
+}
+
+// updateOrApply Updates the peer or applies it in server-side apply mode
+func (or *ResourceObject) updateOrApply(ctx context.Context) error {
+	if or.serverSideApply != nil {
+		return or.apply(ctx)
+	}
+	return or.Update(ctx)
+}
+
 // CreatePeer Creates a resource peer on the Cluster site (at the image of the resource in memory)
-func (or *ResourceObject) CreatePeer() error {
+func (or *ResourceObject) CreatePeer(ctx context.Context) error {
 	if !or.createObj {
 		return errors.New("Peer is presumed yet existing on its end:" + or.Index())
 	}
 
-	if err := or.Create(); err != nil {
+	if err := or.createOrApply(ctx); err != nil {
 		return err
 	}
 
@@ -222,6 +334,40 @@ func (or *ResourceObject) CreatePeer() error {
 	return nil
 }
 
+// DeletePeer Deletes the resource peer on the Cluster site with the propagation policy provided (empty for the default policy)
+// A peer already deleted is not considered as an error.
+func (or *ResourceObject) DeletePeer(ctx context.Context, propagation metav1.DeletionPropagation) error {
+	if or.createObj {
+		return errors.New("Peer is presumed not existing on its end:" + or.Index())
+	}
+
+	if err := or.DeleteWithPropagation(ctx, propagation); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+		or.createObj = true
+	}
+
+	return nil
+}
+
+// IsPeerGone Get again the peer to know if it no longer exists on the Cluster. If so, the resource is set to be created.
+func (or *ResourceObject) IsPeerGone(ctx context.Context) (bool, error) {
+	if or.createObj {
+		return true, nil
+	}
+
+	if err := or.Get(ctx); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		or.createObj = true
+		return true, nil
+	}
+
+	return false, nil
+}
+
 // TODO: Why not comparing the whole key NGVK instead of the only N (NamespacedName) ?
 func checkKeys(sKey string, newObj k8sclient.Object) error {
 	newKey, err := oktngvk.New(newObj)
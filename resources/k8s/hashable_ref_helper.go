@@ -92,24 +92,26 @@ func (hr *HashableRefHelper) AddMetaLabelValues(keys ...string) {
 	}
 }
 
-// AddMetaAnnotations Add all annotations (except the hash itself!!!) to the reference for hash computation
+// AddMetaAnnotations Add all annotations (except the hash annotations themselves!!!) to the reference for hash computation
 func (hr *HashableRefHelper) AddMetaAnnotations() {
 	annotations := hr.GetAnnotations()
 
-	// Backup existing has (if any)
-	hash, exist := annotations[okthash.OKTHashAnnotationName]
-
-	if exist {
-		delete(annotations, okthash.OKTHashAnnotationName)
+	// Backup existing hashes (if any)
+	hashes := map[string]string{}
+	for name, value := range annotations {
+		if okthash.IsHashAnnotation(name) {
+			hashes[name] = value
+			delete(annotations, name)
+		}
 	}
 
 	// Add
 	//hr.addSortedStringMap(annotations)
 	hr.add(annotations)
 
-	// Restore hash annotation ?
-	if exist {
-		annotations[okthash.OKTHashAnnotationName] = hash
+	// Restore hash annotations ?
+	for name, value := range hashes {
+		annotations[name] = value
 	}
 }
 
@@ -0,0 +1,177 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package k8s
+
+import (
+	"fmt"
+
+	k8sapp "k8s.io/api/apps/v1"
+	k8sbatch "k8s.io/api/batch/v1"
+	k8score "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ObjectReadiness Tell if a K8S object, as read on the Cluster, is ready and if not, the reason why:
+//   - Deployment: the observed generation is up to date and all the replicas are updated and available
+//   - StatefulSet: the observed generation is up to date, a rolling update is complete and all the replicas are ready
+//   - DaemonSet: the observed generation is up to date and the pods scheduled are all updated and ready
+//   - Job: the job is complete
+//   - PersistentVolumeClaim: the claim is bound
+//   - Service: a LoadBalancer service has an ingress point, the other types are always ready
+//   - Pod: the Ready condition is true, or the pod succeeded
+//   - any other object with status conditions: its Ready condition, if any, is true
+//
+// An object of another kind is always ready.
+func ObjectReadiness(obj runtime.Object) (ready bool, reason string) {
+	switch o := obj.(type) {
+	case *k8sapp.Deployment:
+		return deploymentReadiness(o)
+	case *k8sapp.StatefulSet:
+		return statefulSetReadiness(o)
+	case *k8sapp.DaemonSet:
+		return daemonSetReadiness(o)
+	case *k8sbatch.Job:
+		return jobReadiness(o)
+	case *k8score.PersistentVolumeClaim:
+		if o.Status.Phase != k8score.ClaimBound {
+			return false, "claim not bound"
+		}
+		return true, ""
+	case *k8score.Service:
+		if o.Spec.Type == k8score.ServiceTypeLoadBalancer && len(o.Status.LoadBalancer.Ingress) == 0 {
+			return false, "no load balancer ingress"
+		}
+		return true, ""
+	case *k8score.Pod:
+		return podReadiness(o)
+	}
+	return conditionsReadiness(obj)
+}
+
+func replicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func deploymentReadiness(o *k8sapp.Deployment) (bool, string) {
+	if o.Status.ObservedGeneration < o.Generation {
+		return false, "generation not yet observed"
+	}
+	for _, cond := range o.Status.Conditions {
+		if cond.Type == k8sapp.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return false, "progress deadline exceeded"
+		}
+	}
+	desired := replicas(o.Spec.Replicas)
+	if o.Status.UpdatedReplicas < desired {
+		return false, fmt.Sprintf("%d/%d replicas updated", o.Status.UpdatedReplicas, desired)
+	}
+	if o.Status.AvailableReplicas < desired {
+		return false, fmt.Sprintf("%d/%d replicas available", o.Status.AvailableReplicas, desired)
+	}
+	return true, ""
+}
+
+func statefulSetReadiness(o *k8sapp.StatefulSet) (bool, string) {
+	if o.Status.ObservedGeneration < o.Generation {
+		return false, "generation not yet observed"
+	}
+	desired := replicas(o.Spec.Replicas)
+	rolling := o.Spec.UpdateStrategy.Type != k8sapp.OnDeleteStatefulSetStrategyType
+	if rolling && o.Status.UpdateRevision != o.Status.CurrentRevision {
+		return false, fmt.Sprintf("%d/%d replicas updated", o.Status.UpdatedReplicas, desired)
+	}
+	if o.Status.ReadyReplicas < desired {
+		return false, fmt.Sprintf("%d/%d replicas ready", o.Status.ReadyReplicas, desired)
+	}
+	return true, ""
+}
+
+func daemonSetReadiness(o *k8sapp.DaemonSet) (bool, string) {
+	if o.Status.ObservedGeneration < o.Generation {
+		return false, "generation not yet observed"
+	}
+	desired := o.Status.DesiredNumberScheduled
+	if o.Status.UpdatedNumberScheduled < desired {
+		return false, fmt.Sprintf("%d/%d pods updated", o.Status.UpdatedNumberScheduled, desired)
+	}
+	if o.Status.NumberReady < desired {
+		return false, fmt.Sprintf("%d/%d pods ready", o.Status.NumberReady, desired)
+	}
+	return true, ""
+}
+
+func jobReadiness(o *k8sbatch.Job) (bool, string) {
+	for _, cond := range o.Status.Conditions {
+		if cond.Status != k8score.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case k8sbatch.JobComplete:
+			return true, ""
+		case k8sbatch.JobFailed:
+			return false, "job failed: " + cond.Message
+		}
+	}
+	return false, "job not complete"
+}
+
+func podReadiness(o *k8score.Pod) (bool, string) {
+	if o.Status.Phase == k8score.PodSucceeded {
+		return true, ""
+	}
+	for _, cond := range o.Status.Conditions {
+		if cond.Type == k8score.PodReady && cond.Status == k8score.ConditionTrue {
+			return true, ""
+		}
+	}
+	return false, "pod not ready (" + string(o.Status.Phase) + ")"
+}
+
+// conditionsReadiness Check the Ready condition in the status of any object (custom resources,...)
+func conditionsReadiness(obj runtime.Object) (bool, string) {
+	var content map[string]interface{}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return true, ""
+		}
+	}
+
+	conditions, found, err := unstructured.NestedSlice(content, "status", "conditions")
+	if err != nil || !found {
+		return true, ""
+	}
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+		if cond["status"] == string(k8score.ConditionTrue) {
+			return true, ""
+		}
+		reason, _ := cond["reason"].(string)
+		if reason == "" {
+			reason = "Ready condition not true"
+		}
+		return false, reason
+	}
+	return true, ""
+}
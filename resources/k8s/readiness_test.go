@@ -0,0 +1,82 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8sapp "k8s.io/api/apps/v1"
+	k8sbatch "k8s.io/api/batch/v1"
+	k8score "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestObjectReadiness(t *testing.T) {
+	three := int32(3)
+	generation := metav1.ObjectMeta{Generation: 2}
+
+	testCases := []struct {
+		name   string
+		obj    runtime.Object
+		ready  bool
+		reason string
+	}{
+		{"deployment available", &k8sapp.Deployment{ObjectMeta: generation, Spec: k8sapp.DeploymentSpec{Replicas: &three},
+			Status: k8sapp.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 3, AvailableReplicas: 3}}, true, ""},
+		{"deployment old generation", &k8sapp.Deployment{ObjectMeta: generation, Spec: k8sapp.DeploymentSpec{Replicas: &three},
+			Status: k8sapp.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 3, AvailableReplicas: 3}}, false, "generation not yet observed"},
+		{"deployment progressing", &k8sapp.Deployment{ObjectMeta: generation, Spec: k8sapp.DeploymentSpec{Replicas: &three},
+			Status: k8sapp.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 3, AvailableReplicas: 1}}, false, "1/3 replicas available"},
+		{"deployment stuck", &k8sapp.Deployment{Status: k8sapp.DeploymentStatus{Conditions: []k8sapp.DeploymentCondition{
+			{Type: k8sapp.DeploymentProgressing, Status: k8score.ConditionFalse, Reason: "ProgressDeadlineExceeded"}}}}, false, "progress deadline exceeded"},
+		{"statefulset ready", &k8sapp.StatefulSet{Spec: k8sapp.StatefulSetSpec{Replicas: &three},
+			Status: k8sapp.StatefulSetStatus{ReadyReplicas: 3, CurrentRevision: "r1", UpdateRevision: "r1"}}, true, ""},
+		{"statefulset rolling update", &k8sapp.StatefulSet{Spec: k8sapp.StatefulSetSpec{Replicas: &three},
+			Status: k8sapp.StatefulSetStatus{ReadyReplicas: 3, UpdatedReplicas: 1, CurrentRevision: "r1", UpdateRevision: "r2"}}, false, "1/3 replicas updated"},
+		{"statefulset starting", &k8sapp.StatefulSet{Status: k8sapp.StatefulSetStatus{ReadyReplicas: 0}}, false, "0/1 replicas ready"},
+		{"daemonset ready", &k8sapp.DaemonSet{Status: k8sapp.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberReady: 2}}, true, ""},
+		{"daemonset starting", &k8sapp.DaemonSet{Status: k8sapp.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberReady: 1}}, false, "1/2 pods ready"},
+		{"job complete", &k8sbatch.Job{Status: k8sbatch.JobStatus{Conditions: []k8sbatch.JobCondition{
+			{Type: k8sbatch.JobComplete, Status: k8score.ConditionTrue}}}}, true, ""},
+		{"job failed", &k8sbatch.Job{Status: k8sbatch.JobStatus{Conditions: []k8sbatch.JobCondition{
+			{Type: k8sbatch.JobFailed, Status: k8score.ConditionTrue, Message: "backoff limit"}}}}, false, "job failed: backoff limit"},
+		{"job running", &k8sbatch.Job{}, false, "job not complete"},
+		{"pvc bound", &k8score.PersistentVolumeClaim{Status: k8score.PersistentVolumeClaimStatus{Phase: k8score.ClaimBound}}, true, ""},
+		{"pvc pending", &k8score.PersistentVolumeClaim{Status: k8score.PersistentVolumeClaimStatus{Phase: k8score.ClaimPending}}, false, "claim not bound"},
+		{"service cluster ip", &k8score.Service{}, true, ""},
+		{"service lb pending", &k8score.Service{Spec: k8score.ServiceSpec{Type: k8score.ServiceTypeLoadBalancer}}, false, "no load balancer ingress"},
+		{"service lb", &k8score.Service{Spec: k8score.ServiceSpec{Type: k8score.ServiceTypeLoadBalancer}, Status: k8score.ServiceStatus{
+			LoadBalancer: k8score.LoadBalancerStatus{Ingress: []k8score.LoadBalancerIngress{{IP: "10.0.0.1"}}}}}, true, ""},
+		{"pod ready", &k8score.Pod{Status: k8score.PodStatus{Phase: k8score.PodRunning, Conditions: []k8score.PodCondition{
+			{Type: k8score.PodReady, Status: k8score.ConditionTrue}}}}, true, ""},
+		{"pod pending", &k8score.Pod{Status: k8score.PodStatus{Phase: k8score.PodPending}}, false, "pod not ready (Pending)"},
+		{"pod succeeded", &k8score.Pod{Status: k8score.PodStatus{Phase: k8score.PodSucceeded}}, true, ""},
+		{"configmap", &k8score.ConfigMap{}, true, ""},
+		{"custom resource ready", &unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}}}}}, true, ""},
+		{"custom resource not ready", &unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "Provisioning"}}}}}, false, "Provisioning"},
+		{"typed object with conditions", &k8score.Node{Status: k8score.NodeStatus{Conditions: []k8score.NodeCondition{
+			{Type: k8score.NodeReady, Status: k8score.ConditionFalse, Reason: "KubeletNotReady"}}}}, false, "KubeletNotReady"},
+	}
+	for _, tc := range testCases {
+		ready, reason := ObjectReadiness(tc.obj)
+		require.Equal(t, tc.ready, ready, tc.name)
+		require.Equal(t, tc.reason, reason, tc.name)
+	}
+}
@@ -49,7 +49,7 @@ func (r *StatefulSetHelper) GetReadyPodsCount() (ready, remaining int32) {
 
 // GetRunningPodsCount Returns running pods count and remaining count with regard to the total count deployed.
 // Note that if the StatefulSet is not yet deployed on Cluster, the remaining count is always 0
-func (r *StatefulSetHelper) GetRunningPodsCount() (running, remaining int32, err error) {
+func (r *StatefulSetHelper) GetRunningPodsCount(ctx context.Context) (running, remaining int32, err error) {
 	if r.GetResourceObject().IsCreation() {
 		return 0, 0, nil
 	}
@@ -64,7 +64,7 @@ func (r *StatefulSetHelper) GetRunningPodsCount() (running, remaining int32, err
 
 	k8scli := kcli.Client
 
-	if err = k8scli.List(context.TODO(), podList, listOpts...); err != nil {
+	if err = k8scli.List(ctx, podList, listOpts...); err != nil {
 		return 0, 0, errors.New("Unable to convert OKT client to Kube client")
 	}
 
@@ -329,3 +329,56 @@ func TestMutableStatefulSetObject(t *testing.T) {
 	//kappv1.SetDefaults_StatefulSet(statefullset)
 	//require.True(t, tObj.NeedResync(), "Some defaults have been added")
 }
+
+func TestMutableStatefulSetDrift(t *testing.T) {
+	tObj := myMutableStatefulSet{}
+	_ = tObj.Init(nil, "myns", "myname")
+	require.Equal(t, oktres.DriftPolicyRevert, tObj.GetDriftPolicy(), "Revert is the default policy")
+
+	drifted, err := tObj.DetectDrift(tObj.GetHashableRef())
+	require.NoError(t, err)
+	require.False(t, drifted, "No drift without any hash stored on the object")
+
+	// Simulate a peer as updated by OKT
+	tObj.MutateWithInitialData()
+	tObj.MutateWithCR()
+	tObj.UpdateSyncStatus(tObj.GetHashableRef())
+
+	drifted, err = tObj.DetectDrift(tObj.GetHashableRef())
+	require.NoError(t, err)
+	require.False(t, drifted, "The peer is as updated by OKT")
+
+	// Simulate an out-of-band modification (kubectl edit)
+	replicas := *tObj.Expected.Spec.Replicas + 1
+	tObj.Expected.Spec.Replicas = &replicas
+	drifted, err = tObj.DetectDrift(tObj.GetHashableRef())
+	require.NoError(t, err)
+	require.True(t, drifted, "A modification of a hashable field must be detected")
+	require.True(t, tObj.IsDrifted())
+
+	// Accept it
+	require.NoError(t, tObj.AcceptDrift(tObj.GetHashableRef()))
+	drifted, err = tObj.DetectDrift(tObj.GetHashableRef())
+	require.NoError(t, err)
+	require.False(t, drifted, "The drift is accepted as the new reference")
+}
+
+func TestMutableStatefulSetDiff(t *testing.T) {
+	tObj := myMutableStatefulSet{}
+	_ = tObj.Init(nil, "myns", "myname")
+
+	changes, err := tObj.DiffFromPeer(tObj.GetHashableRef())
+	require.NoError(t, err)
+	require.Empty(t, changes, "No change without a snapshot of the peer")
+
+	// Simulate the peer as get from the cluster
+	tObj.MutateWithInitialData()
+	tObj.MutateWithCR()
+	require.NoError(t, tObj.SnapshotPeer(tObj.GetHashableRef()))
+
+	replicas := int32(5)
+	tObj.Expected.Spec.Replicas = &replicas
+	changes, err = tObj.DiffFromPeer(tObj.GetHashableRef())
+	require.NoError(t, err)
+	require.Equal(t, []string{"spec.replicas: 4 -> 5"}, changes)
+}
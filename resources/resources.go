@@ -15,7 +15,11 @@
 package resources
 
 import (
+	"context"
+
 	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+	oktngvk "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/ngvk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -34,18 +38,102 @@ type ResourceInfo interface {
 	KindName() string
 }
 
+// KeyedResource is a resource able to provide its NGVK key (NamespacedName Group Version Kind), like the K8S resources
+type KeyedResource interface {
+	GetNGVK() oktngvk.NGVK
+}
+
 // Resource a resource manageable by the OKT Reconciler. It is synchronized with its peer on cluster and
-// offers utilities for its creation.
-// A simple Resource can NOT be mutated (see MutableResource), but only created.
+// offers utilities for its creation and its deletion.
+// A simple Resource can NOT be mutated (see MutableResource), but only created or deleted.
 type Resource interface {
 	ResourceInfo
 
 	IsCreation() bool
-	CreatePeer() error
-	SyncFromPeer() error
+	CreatePeer(ctx context.Context) error
+	SyncFromPeer(ctx context.Context) error
+	// DeletePeer Request the deletion of the peer with a propagation policy (empty for the default one).
+	// The deletion can be completed later (finalizers, foreground propagation), see IsPeerGone()
+	DeletePeer(ctx context.Context, propagation metav1.DeletionPropagation) error
+	// IsPeerGone Check again the existence of the peer and tells if it no longer exists
+	IsPeerGone(ctx context.Context) (bool, error)
 	Params
 }
 
+// ServerSideApplyResource is a resource which can be created and updated with the server-side apply method instead of plain Create and Update requests.
+type ServerSideApplyResource interface {
+	// EnableServerSideApply Create and update the peer with the server-side apply method as the field manager provided
+	EnableServerSideApply(fieldManager string, forceConflicts bool)
+	IsServerSideApplyEnabled() bool
+	// PrepareApply Reset the object before its mutations, thus the applied fields are only the ones set by the mutations
+	PrepareApply() error
+}
+
+// DependentResource is a resource depending on other resources of the same registry, designated by their index.
+// It is created only once these resources exist on the Cluster and are ready (see Readiness).
+type DependentResource interface {
+	// DependsOn Add the indexes of the resources to create before this one
+	DependsOn(indexes ...string)
+	Dependencies() []string
+}
+
+// Readiness is a resource able to tell if its peer is ready to be used, i.e. by the resources depending on it.
+// A resource not implementing it is considered ready as soon as its peer exists.
+type Readiness interface {
+	// IsReady Tell if the peer is ready and, if not, the reason why
+	IsReady() (ready bool, reason string)
+}
+
+// SpecAwareResource is a resource informed, at its registration, whether the CR spec changed since the last successful reconciliation
+type SpecAwareResource interface {
+	SetSpecChanged(changed bool)
+	// SpecChanged Tells if the CR spec changed since the last successful reconciliation
+	SpecChanged() bool
+	// IsSpecOnlyMutation Tells if the mutations of the resource only depend on the CR spec (and on the initial data), thus if they
+	// can be skipped while the spec is unchanged
+	IsSpecOnlyMutation() bool
+}
+
+// DriftPolicy defines how to treat the drift of a resource, i.e. an out-of-band modification of its peer (kubectl edit,...)
+type DriftPolicy string
+
+const (
+	// DriftPolicyRevert The drift is reported and the peer is updated back to the expected state (default)
+	DriftPolicyRevert DriftPolicy = "Revert"
+	// DriftPolicyTolerate The drift is reported once and accepted. It does not trigger an update by itself.
+	DriftPolicyTolerate DriftPolicy = "Tolerate"
+	// DriftPolicyReport The drift is reported at each reconciliation while it lasts. It does not trigger an update by itself.
+	DriftPolicyReport DriftPolicy = "Report"
+)
+
+// DriftDetector is a mutable resource able to detect the drift of its peer by comparing the hashable fields
+// of the peer object with the fingerprint (hash) stored at its last update by OKT.
+type DriftDetector interface {
+	// DetectDrift Must be called on the peer object, before any mutation. A drift to revert makes the resource to resync.
+	DetectDrift(ref okthash.HashableRef) (bool, error)
+	// IsDrifted Result of the last call to DetectDrift()
+	IsDrifted() bool
+	// IsLegacyPeer Tells if the last call to DetectDrift() found a peer written by a former version, without the fingerprint of the
+	// expected object as last applied. If not updated, such a peer must be recorded (see RecordPeer()) instead of being checked.
+	IsLegacyPeer() bool
+	// AcceptDrift Make the peer object (drifted or not) the new reference for the modifications detection
+	AcceptDrift(ref okthash.HashableRef) error
+	GetDriftPolicy() DriftPolicy
+	// RecordPeer Store the fingerprint of the peer as returned by the Cluster. Must be called after each creation or update of the peer.
+	RecordPeer(ctx context.Context, ref okthash.HashableRef) error
+	// SaveAcceptedDrift Store the fingerprint of the peer accepted by AcceptDrift(), if any, when the peer is not updated
+	SaveAcceptedDrift(ctx context.Context) error
+}
+
+// Differ is a mutable resource able to report the field-level changes made on its peer by the mutations,
+// in the scope of its HashableRef.
+type Differ interface {
+	// SnapshotPeer Keep a copy of the hashable fields of the peer object. Must be called before any mutation.
+	SnapshotPeer(ref okthash.HashableRef) error
+	// DiffFromPeer Return the changes, since the snapshot, in a human-readable form ("spec.replicas: 3 -> 5")
+	DiffFromPeer(ref okthash.HashableRef) ([]string, error)
+}
+
 // MutableResource provides all the required tools to process a mutation on a resource having a Mutator (mandatory)
 // All things driven with idempotency in mind.
 type MutableResource interface {
@@ -71,7 +159,7 @@ type MutableResource interface {
 	// Verify  that object keys (index) remains the same as expected, even after a modifications
 	CheckExpectedKey() error
 
-	UpdatePeer() error
+	UpdatePeer(ctx context.Context) error
 }
 
 // Mutator is an interface providing mutation function based on hash computation to determines changes on the resource
@@ -14,6 +14,8 @@
 
 package results
 
+import "errors"
+
 // ErrGiveUp defines an error which cause the end of the reconciliation to the current state
 type ErrGiveUp struct {
 	reason error
@@ -22,12 +24,21 @@ type ErrGiveUp struct {
 // blank assignment to verify that ErrGiveUp implements error interface
 var _ error = &ErrGiveUp{}
 
-// Reason Set the reason (actualy an alarming error) why we give up the current reconciliation
-// In conformance with the error interface requirement the returned error is the error (ErrGiveUp) itself
-// Providing no reason (nil) is about not alarming issue, just want to stop the reconciliation until a new event occur
+// Reason Return a GiveUp error with the reason (actualy an alarming error) why we give up the current reconciliation
+// The error it is called on is left unchanged, thus several reconciliations can give up at the same time for their own reason.
+// Providing no reason (nil) is about not alarming issue, just want to stop the reconciliation until a new event occur: the
+// ErrGiveUpReconciliation sentinel is then returned.
 func (e *ErrGiveUp) Reason(alarmingError error) error {
-	e.reason = alarmingError
-	return e
+	if alarmingError == nil {
+		return ErrGiveUpReconciliation
+	}
+	return &ErrGiveUp{reason: alarmingError}
+}
+
+// Is Tells if the target is the ErrGiveUpReconciliation sentinel, thus errors.Is(err, ErrGiveUpReconciliation) matches any GiveUp error
+// whatever its reason
+func (e *ErrGiveUp) Is(target error) bool {
+	return target == ErrGiveUpReconciliation
 }
 
 // AlarmingReasonToGiveup Get the reason (actualy the error) why we give up the current reconciliation
@@ -43,5 +54,11 @@ func (e ErrGiveUp) Error() string {
 	return "Given up on not alarming issue..."
 }
 
-// ErrGiveUpReconciliation the pointer on the exported instance of an error of type ErrGiveUp
+// ErrGiveUpReconciliation the pointer on the exported instance of an error of type ErrGiveUp, without reason.
+// Use its Reason() method to get a GiveUp error with a reason and errors.Is(err, ErrGiveUpReconciliation) or IsGiveUp() to check an error.
 var ErrGiveUpReconciliation *ErrGiveUp = &ErrGiveUp{}
+
+// IsGiveUp Tells if the error is a GiveUp error, whatever its reason
+func IsGiveUp(err error) bool {
+	return errors.Is(err, ErrGiveUpReconciliation)
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package results
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultMaxRequeueDelay is the maximum requeue delay of the default policy
+const DefaultMaxRequeueDelay = 6 * time.Hour
+
+// RequeuePolicy computes the delay before a new reconciliation, for an operation which requests a requeue (on error or after a delay)
+type RequeuePolicy interface {
+	// RequeueAfter Return the requeue delay of an operation, regarding the delay requested when the operation is added to the results and
+	// the attempt number, i.e. the count of consecutive requeued reconciliations of the CR, including the current one (1 for the first one)
+	RequeueAfter(operation OperationResult, requested time.Duration, attempt uint) time.Duration
+}
+
+// DefaultRequeuePolicy keeps the delay requested, up to 6 hours
+var DefaultRequeuePolicy RequeuePolicy = &FixedRequeuePolicy{Max: DefaultMaxRequeueDelay}
+
+// FixedRequeuePolicy requeues after the same delay at each attempt
+type FixedRequeuePolicy struct {
+	Delay time.Duration // The delay requested is kept if 0
+	Max   time.Duration // No maximum if 0
+}
+
+// RequeueAfter RequeuePolicy implementation
+func (p *FixedRequeuePolicy) RequeueAfter(operation OperationResult, requested time.Duration, attempt uint) time.Duration {
+	delay := requested
+	if p.Delay > 0 {
+		delay = p.Delay
+	}
+	if p.Max > 0 && delay > p.Max {
+		delay = p.Max
+	}
+	return delay
+}
+
+// ExponentialRequeuePolicy multiplies the delay by a factor at each attempt, up to a maximum delay, then adds a random jitter
+// to spread the requeues of several CRs failing at the same time
+type ExponentialRequeuePolicy struct {
+	Base   time.Duration // Delay of the first attempt. The delay requested is taken if 0
+	Factor float64       // 2 if lower than 1
+	Max    time.Duration // No maximum if 0
+	Jitter float64       // The delay is increased by a random duration up to Jitter * delay. No jitter if 0
+}
+
+// NewExponentialRequeuePolicy Return a policy doubling the delay at each attempt from base up to max, with a jitter up to 10%
+func NewExponentialRequeuePolicy(base, max time.Duration) *ExponentialRequeuePolicy {
+	return &ExponentialRequeuePolicy{Base: base, Factor: 2, Max: max, Jitter: 0.1}
+}
+
+// RequeueAfter RequeuePolicy implementation
+func (p *ExponentialRequeuePolicy) RequeueAfter(operation OperationResult, requested time.Duration, attempt uint) time.Duration {
+	delay := requested
+	if p.Base > 0 {
+		delay = p.Base
+	}
+	factor := p.Factor
+	if factor < 1 {
+		factor = 2
+	}
+	if attempt > 1 {
+		growth := float64(delay) * math.Pow(factor, float64(attempt-1))
+		if growth > math.MaxInt64 {
+			growth = math.MaxInt64
+		}
+		delay = time.Duration(growth)
+	}
+	if p.Max > 0 && delay > p.Max {
+		delay = p.Max
+	}
+	if p.Jitter > 0 {
+		delay = wait.Jitter(delay, p.Jitter)
+	}
+	return delay
+}
+
+// TableRequeuePolicy sets the delay of each operation type listed, the other ones are managed by a fallback policy
+type TableRequeuePolicy struct {
+	Delays   map[OperationResult]time.Duration
+	Fallback RequeuePolicy // DefaultRequeuePolicy if nil
+}
+
+// RequeueAfter RequeuePolicy implementation
+func (p *TableRequeuePolicy) RequeueAfter(operation OperationResult, requested time.Duration, attempt uint) time.Duration {
+	if delay, listed := p.Delays[operation]; listed {
+		return delay
+	}
+	if p.Fallback == nil {
+		return DefaultRequeuePolicy.RequeueAfter(operation, requested, attempt)
+	}
+	return p.Fallback.RequeueAfter(operation, requested, attempt)
+}
+
+// RequeueAttempts counts, per CR, the consecutive reconciliations that ended with a requeue. It is shared by the requests of a reconciler.
+type RequeueAttempts struct {
+	lock   sync.Mutex
+	counts map[string]uint
+}
+
+// NewRequeueAttempts Allocates a new attempts counter
+func NewRequeueAttempts() *RequeueAttempts {
+	return &RequeueAttempts{counts: make(map[string]uint)}
+}
+
+// Current Return the attempt number of the reconciliation of a CR being served: 1 if the previous one has not been requeued
+func (a *RequeueAttempts) Current(key string) uint {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.counts[key] + 1
+}
+
+// Done Count a reconciliation of a CR if it has been requeued, or reset the count if not
+func (a *RequeueAttempts) Done(key string, requeued bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if requeued {
+		a.counts[key]++
+		return
+	}
+	delete(a.counts, key)
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package results
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedRequeuePolicy(t *testing.T) {
+	require.Equal(t, 3*time.Second, DefaultRequeuePolicy.RequeueAfter(OperationResultCRUDError, 3*time.Second, 5), "The delay requested is kept")
+	require.Equal(t, DefaultMaxRequeueDelay, DefaultRequeuePolicy.RequeueAfter(OperationResultSameStatusError, 7*time.Hour, 1))
+
+	policy := &FixedRequeuePolicy{Delay: 10 * time.Second}
+	require.Equal(t, 10*time.Second, policy.RequeueAfter(OperationResultCRUDError, 3*time.Second, 1))
+	require.Equal(t, 10*time.Second, policy.RequeueAfter(OperationResultCRUDError, 3*time.Second, 8))
+}
+
+func TestExponentialRequeuePolicy(t *testing.T) {
+	policy := &ExponentialRequeuePolicy{Max: time.Minute}
+	for attempt, expected := range []time.Duration{3, 6, 12, 24, 48, 60, 60} {
+		require.Equal(t, expected*time.Second, policy.RequeueAfter(OperationResultCRUDError, 3*time.Second, uint(attempt+1)))
+	}
+
+	policy = &ExponentialRequeuePolicy{Base: time.Second, Factor: 3}
+	require.Equal(t, 9*time.Second, policy.RequeueAfter(OperationResultCRUDError, 3*time.Second, 3), "The base delay overrides the one requested")
+
+	policy = NewExponentialRequeuePolicy(10*time.Second, time.Hour)
+	for i := 0; i < 100; i++ {
+		delay := policy.RequeueAfter(OperationResultCRUDError, 0, 2)
+		require.GreaterOrEqual(t, delay, 20*time.Second)
+		require.LessOrEqual(t, delay, 22*time.Second, "The jitter is up to 10%")
+	}
+}
+
+func TestTableRequeuePolicy(t *testing.T) {
+	policy := &TableRequeuePolicy{Delays: map[OperationResult]time.Duration{OperationResultCreateDelayed: 30 * time.Second}}
+	require.Equal(t, 30*time.Second, policy.RequeueAfter(OperationResultCreateDelayed, 4*time.Second, 1))
+	require.Equal(t, 3*time.Second, policy.RequeueAfter(OperationResultCRUDError, 3*time.Second, 1))
+
+	policy.Fallback = &FixedRequeuePolicy{Delay: time.Minute}
+	require.Equal(t, time.Minute, policy.RequeueAfter(OperationResultCRUDError, 3*time.Second, 1))
+}
+
+func TestRequeueAttempts(t *testing.T) {
+	attempts := NewRequeueAttempts()
+	require.Equal(t, uint(1), attempts.Current("ns/cr"))
+
+	attempts.Done("ns/cr", true)
+	attempts.Done("ns/cr", true)
+	require.Equal(t, uint(3), attempts.Current("ns/cr"))
+	require.Equal(t, uint(1), attempts.Current("ns/other"))
+
+	attempts.Done("ns/cr", false)
+	require.Equal(t, uint(1), attempts.Current("ns/cr"), "Reset after a reconciliation not requeued")
+}
+
+func TestResultsRequeuePolicy(t *testing.T) {
+	myErr := errors.New("My dummy error")
+
+	// Default policy: the error is returned, the delay is capped
+	results := NewResultList()
+	results.AddOp(nil, OperationResultCRUDError, myErr, 65000)
+	rr, err := results.ConsolidatedSigsK8S()
+	require.Equal(t, DefaultMaxRequeueDelay, rr.RequeueAfter)
+	require.Equal(t, myErr, err)
+
+	// Policy set: the delay is computed for the attempt and returned with the error
+	results = NewResultList()
+	results.UseRequeuePolicy(&ExponentialRequeuePolicy{}, 3)
+	results.AddOpSuccess(nil, OperationResultCreated)
+	results.AddOp(nil, OperationResultCRUDError, myErr, 3)
+	rr, err = results.ConsolidatedSigsK8S()
+	require.True(t, rr.Requeue)
+	require.Equal(t, 12*time.Second, rr.RequeueAfter)
+	require.Equal(t, myErr, err)
+
+	// An immediate requeue on error stays managed by the manager
+	results = NewResultList()
+	results.UseRequeuePolicy(&ExponentialRequeuePolicy{}, 3)
+	results.AddOp(nil, OperationResultCRUDError, myErr, 0)
+	rr, err = results.ConsolidatedSigsK8S()
+	require.Equal(t, time.Duration(0), rr.RequeueAfter)
+	require.Equal(t, myErr, err)
+}
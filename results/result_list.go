@@ -15,6 +15,7 @@
 package results
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -28,9 +29,10 @@ import (
 type opResInfo struct {
 	operation OperationResult
 	error
-	resource            oktres.ResourceInfo
-	requeue             bool
-	requeueAfterSeconds uint16
+	resource     oktres.ResourceInfo
+	requeue      bool
+	requeueAfter time.Duration
+	details      []string
 }
 
 // opStats Cumulated indicators on operations
@@ -92,6 +94,9 @@ type resultList struct {
 	opResInfoList []*opResInfo
 	consolidated  opResInfo
 	opStats
+
+	policy  RequeuePolicy // nil for the default policy
+	attempt uint
 }
 
 // blank assignment to verify that ResultList implements Result
@@ -100,34 +105,30 @@ var _ Results = &resultList{}
 // setRequeue Set result with or without error and with or without requeueing.
 // Note that in case of error, requeueing if forced (0 seconds by default), except for the GivUp error.
 // ErrGiveUpReconciliation means we'll try to abort the reconciliation processs. No requeing is possible on such "error"
-// Max duration is 6 hours so 21600 seconds
-func (r *opResInfo) setRequeue(requeueAfterSeconds uint16) {
+// The requeue delay is the one computed by the requeue policy (see requeueDelay())
+func (r *opResInfo) setRequeue(requeueAfter time.Duration) {
 	// Treat special case of GiveUp error and return
-	if r.error == ErrGiveUpReconciliation {
+	if IsGiveUp(r.error) {
 		r.requeue = false
-		r.requeueAfterSeconds = 0
+		r.requeueAfter = 0
 		return
 	}
 
-	if requeueAfterSeconds > 21600 {
-		requeueAfterSeconds = 21600
-	}
-
-	r.requeueAfterSeconds = requeueAfterSeconds
+	r.requeueAfter = requeueAfter
 
 	// As soon as it is an error, a requeue is requested.
 	// A requeue is requested too, in case of NO error and the requeue time is not 0 => No immediate requeue without error!
-	if r.error != nil || r.requeueAfterSeconds > 0 {
+	if r.error != nil || r.requeueAfter > 0 {
 		r.requeue = true
 	}
 }
 
 // getSigsK8SResult Return Result in it sigs.k8s.io reconcile form
 func (r opResInfo) getSigsK8SResult() (reconcile.Result, error) {
-	if r.error == ErrGiveUpReconciliation {
+	if IsGiveUp(r.error) {
 		return reconcile.Result{Requeue: false, RequeueAfter: 0}, nil // Do not return the reason/alarming error, to avoid a requeue request.
 	}
-	return reconcile.Result{Requeue: r.requeue, RequeueAfter: time.Duration(r.requeueAfterSeconds) * time.Second}, r.error
+	return reconcile.Result{Requeue: r.requeue, RequeueAfter: r.requeueAfter}, r.error
 }
 
 // addEntry add a new result and build (as we go) the consolidated result as well
@@ -137,7 +138,7 @@ func (r *resultList) addEntry(entry *opResInfo) error {
 
 	// Compute on-the-go, consolidated result
 	// Track first error only. GiveUpReconciliation is prioritary so it is never overrided
-	if r.consolidated.error == nil || entry.error == ErrGiveUpReconciliation {
+	if r.consolidated.error == nil || IsGiveUp(entry.error) {
 		r.consolidated.error = entry.error
 		r.consolidated.resource = entry.resource
 	}
@@ -147,12 +148,16 @@ func (r *resultList) addEntry(entry *opResInfo) error {
 	// (probably growing up after each occurence)
 	if entry.requeue {
 		if !r.consolidated.requeue ||
-			entry.requeueAfterSeconds < r.consolidated.requeueAfterSeconds ||
+			entry.requeueAfter < r.consolidated.requeueAfter ||
 			entry.operation == OperationResultSameStatusError {
-			r.consolidated.setRequeue(entry.requeueAfterSeconds)
+			r.consolidated.setRequeue(entry.requeueAfter)
 		}
 	}
 
+	if IsGiveUp(entry.error) {
+		r.giveup = true
+	}
+
 	// Update stats
 	r.addToCounters(entry.error, entry.operation)
 
@@ -163,30 +168,80 @@ func (r *resultList) addEntry(entry *opResInfo) error {
 // The consolidated's requeue state can be reset to False on some errors (GiveUp)
 // Return (pass) the added result's error passed as parameter
 func (r *resultList) AddOp(resource oktres.ResourceInfo, result OperationResult, err error, requeueAfterSeconds uint16) error {
-	if err == ErrGiveUpReconciliation {
-		r.giveup = true
-	}
 	entry := opResInfo{
 		resource:  resource,
 		operation: result,
 		error:     err,
 	}
-	entry.setRequeue(requeueAfterSeconds)
+	entry.setRequeue(r.requeueDelay(result, err, requeueAfterSeconds))
 
 	return r.addEntry(&entry)
 }
 
+// requeueDelay Return the requeue delay computed by the policy for an operation which requests a requeue (error or delay), else 0
+func (r *resultList) requeueDelay(result OperationResult, err error, requeueAfterSeconds uint16) time.Duration {
+	if err == nil && requeueAfterSeconds == 0 {
+		return 0
+	}
+	policy := r.policy
+	if policy == nil {
+		policy = DefaultRequeuePolicy
+	}
+	return policy.RequeueAfter(result, time.Duration(requeueAfterSeconds)*time.Second, r.attempt)
+}
+
+// UseRequeuePolicy Set the policy computing the requeue delay of the operations added from now on (default policy if nil).
+// The attempt number is the count of consecutive requeued reconciliations of the CR, including the current one (see RequeueAttempts).
+func (r *resultList) UseRequeuePolicy(policy RequeuePolicy, attempt uint) {
+	r.policy = policy
+	r.attempt = attempt
+}
+
+// Fork Return a new empty list of results with the same requeue policy and attempt number
+func (r *resultList) Fork() Results {
+	fork := &resultList{policy: r.policy, attempt: r.attempt}
+	fork.ResetAllResults()
+	return fork
+}
+
+// Merge Add the results provided to this list. The requeue delays of results of another type than this one are computed again.
+func (r *resultList) Merge(results Results) {
+	if list, ok := results.(*resultList); ok {
+		for _, entry := range list.opResInfoList {
+			copied := *entry
+			r.addEntry(&copied)
+		}
+		return
+	}
+	for _, op := range results.OpList() {
+		entry := opResInfo{resource: op.Resource, operation: op.Operation, error: op.Err, details: op.Details}
+		entry.setRequeue(r.requeueDelay(op.Operation, op.Err, 0))
+		r.addEntry(&entry)
+	}
+}
+
 // AddOpSuccess Add a result operation in case of success (without requeueing!). Use Add if you need to requeue on success.
 // Return (pass) the added result's error
 func (r *resultList) AddOpSuccess(resource oktres.ResourceInfo, result OperationResult) {
 	r.AddOp(resource, result, nil, 0)
 }
 
+// AddOpSuccessWithDetails Same as AddOpSuccess() with some details on the operation (i.e. the changes made by an update)
+func (r *resultList) AddOpSuccessWithDetails(resource oktres.ResourceInfo, result OperationResult, details []string) {
+	entry := opResInfo{
+		resource:  resource,
+		operation: result,
+		details:   details,
+	}
+	entry.setRequeue(0)
+
+	r.addEntry(&entry)
+}
+
 // AddGiveupError Add a GiveUp Reconciliation result to the maintained list of results and maintain a consolidated state
 // Return (pass) the added result's error
 func (r *resultList) AddGiveupError(resource oktres.ResourceInfo, result OperationResult, alarmingReason error) error {
-	ErrGiveUpReconciliation.Reason(alarmingReason)
-	return r.AddOp(resource, result, ErrGiveUpReconciliation, 0)
+	return r.AddOp(resource, result, ErrGiveUpReconciliation.Reason(alarmingReason), 0)
 }
 
 // ResetOpList Delete all elements and re-init the List to 0 element
@@ -197,6 +252,7 @@ func (r *resultList) ResetAllResults() {
 	r.consolidated.requeue = false
 	r.consolidated.setRequeue(0)
 
+	r.giveup = false
 	r.opResInfoList = nil
 	r.opResInfoList = make([]*opResInfo, 0)
 	r.resetCounters()
@@ -206,22 +262,35 @@ func (r *resultList) ResetAllResults() {
 // Unlike ConsolidatedSigsK8S(), this method returns the ErrGiveUpReconciliation status (raised or not) and the current error of the AlarmingReason error
 func (r *resultList) ConsolidatedError() (giveup bool, err error) {
 	err = r.consolidated.error
-	if err == ErrGiveUpReconciliation {
+	var giveupErr *ErrGiveUp
+	if errors.As(err, &giveupErr) {
 		giveup = true
-		err = ErrGiveUpReconciliation.AlarmingReasonToGiveup()
+		err = giveupErr.AlarmingReasonToGiveup()
 	}
 	return giveup, err
 }
 
 // ConsolidatedSigsK8S Return consolidated Result in its sigs.k8s.io reconcile version and the error
-//  In a reconciler (as expected by sigs.k8s.io), 3 outputs are possible:
-//  - Return no error and don't requeue: reconcile.Result{}, nil
-//  - Return no error and requeue: reconcile.Result{Requeue: true}, nil
-//  - Return an error and requeue: reconcile.Result{}, err
+//
+//	In a reconciler (as expected by sigs.k8s.io), 3 outputs are possible:
+//	- Return no error and don't requeue: reconcile.Result{}, nil
+//	- Return no error and requeue: reconcile.Result{Requeue: true}, nil
+//	- Return an error and requeue: reconcile.Result{}, err
+//
+// The requeue delay is the one computed by the requeue policy, if set (see UseRequeuePolicy()), returned together with the error.
 func (r *resultList) ConsolidatedSigsK8S() (reconcile.Result, error) {
 	return r.consolidated.getSigsK8SResult()
 }
 
+// OpList Return a copy of the list of results
+func (r *resultList) OpList() []OpInfo {
+	list := make([]OpInfo, 0, len(r.opResInfoList))
+	for _, entry := range r.opResInfoList {
+		list = append(list, OpInfo{Operation: entry.operation, Resource: entry.resource, Err: entry.error, Details: entry.details})
+	}
+	return list
+}
+
 // DisplayOpList Write results list to logger
 func (r *resultList) DisplayOpList(logger logr.Logger) {
 	for _, entry := range r.opResInfoList {
@@ -229,13 +298,17 @@ func (r *resultList) DisplayOpList(logger logr.Logger) {
 		if entry.resource != nil {
 			resName = entry.resource.KindName()
 		}
+		entryLogger := logger.WithValues("res", resName)
+		if len(entry.details) > 0 {
+			entryLogger = entryLogger.WithValues("details", entry.details)
+		}
 		if entry.error == nil {
-			logger.WithValues("res", resName).Info("Op: " + string(entry.operation))
+			entryLogger.Info("Op: " + string(entry.operation))
 			continue
 		}
-		logger.WithValues("res", resName).Error(entry.error, "Op: "+string(entry.operation))
+		entryLogger.Error(entry.error, "Op: "+string(entry.operation))
 	}
-	logger.Info("Consolidated requeue duration: " + fmt.Sprint(r.consolidated.requeueAfterSeconds) + " seconds")
+	logger.Info("Consolidated requeue duration: " + fmt.Sprint(r.consolidated.requeueAfter.Seconds()) + " seconds")
 }
 
 // NewResultList Allocates a new registry
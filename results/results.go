@@ -22,7 +22,7 @@ import (
 )
 
 // OperationResult is the action result of a CreateOrUpdate call
-//type OperationResult controllerutil.OperationResult
+// type OperationResult controllerutil.OperationResult
 type OperationResult string
 
 const ( // They should complete the sentence "Deployment default/foo has been ..."
@@ -55,6 +55,20 @@ const ( // They should complete the sentence "Deployment default/foo has been ..
 	OperationResultCRIsFinalizing OperationResult = "CR is being deleted and finalizing (has finalizer)"
 	// OperationResultCRFinalizationUpdateError the end of CR finalization failed on update
 	OperationResultCRFinalizationUpdateError OperationResult = "the end of CR finalization failed on update"
+	// OperationResultCRFinalizerAdded the finalizer of the reconciler is added to the CR
+	OperationResultCRFinalizerAdded OperationResult = "finalizer added to the CR"
+	// OperationResultCRFinalizerAddError the finalizer of the reconciler can not be added to the CR
+	OperationResultCRFinalizerAddError OperationResult = "the CR finalizer registration failed on update"
+	// OperationResultCRFinalizationTaskDone a finalization task succeeded
+	OperationResultCRFinalizationTaskDone OperationResult = "CR finalization task done"
+	// OperationResultCRFinalizationTaskError a finalization task failed and will be retried
+	OperationResultCRFinalizationTaskError OperationResult = "CR finalization task failed"
+	// OperationResultCRFinalizationTaskTimeout a finalization task exceeded its timeout and will be retried
+	OperationResultCRFinalizationTaskTimeout OperationResult = "CR finalization task timed out"
+	// OperationResultCRFinalizationTaskAbandoned a finalization task still failing after its give-up delay no longer blocks the finalization
+	OperationResultCRFinalizationTaskAbandoned OperationResult = "CR finalization task abandoned"
+	// OperationResultCRFinalized the CR finalization is completed and the finalizer removed
+	OperationResultCRFinalized OperationResult = "CR finalization completed, finalizer removed"
 
 	///// REGISTRATION for resources
 
@@ -69,8 +83,12 @@ const ( // They should complete the sentence "Deployment default/foo has been ..
 
 	// OperationResultMutationSuccess means that a resource mutation has aborted
 	OperationResultMutationSuccess OperationResult = "resource mutation success"
+	// OperationResultMutationSkipped means that a resource mutation is skipped as the CR spec is unchanged (see AdvancedObject.EnableSpecFastPath())
+	OperationResultMutationSkipped OperationResult = "resource mutation skipped, CR spec unchanged"
 	// OperationResultMutateWithCRError means that there's a pb to mutate the resource with the CR values
 	OperationResultMutateWithCRError OperationResult = "resource.MutateWithCR() on error"
+	// OperationResultDriftDetected means that the resource has been modified on the Cluster out of the reconciler
+	OperationResultDriftDetected OperationResult = "resource drift detected"
 	// OperationResultMutateWithCRAskRequeue means that the resource with the CR values is done and a requeue is requested
 	OperationResultMutateWithCRAskRequeue OperationResult = "resource.MutateWithCR() done with a requeing result"
 
@@ -82,14 +100,30 @@ const ( // They should complete the sentence "Deployment default/foo has been ..
 	OperationResultCreated OperationResult = "resource created"
 	// OperationResultCreateDelayed means that a new resource creation is delayed (not an error)
 	OperationResultCreateDelayed OperationResult = "resources creation delayed"
+	// OperationResultDependencyNotReady means that a new resource creation is delayed until its dependencies exist and are ready (not an error)
+	OperationResultDependencyNotReady OperationResult = "resource creation waiting for its dependencies"
 	// OperationResultUpdated means that an existing resource is updated
 	OperationResultUpdated OperationResult = "resource updated"
 	// OperationResultDeleted means that an existing resource is deleted
 	OperationResultDeleted OperationResult = "resource deleted"
+	// OperationResultDeleteInProgress means that the deletion of a resource is requested but the resource still exists
+	OperationResultDeleteInProgress OperationResult = "resource deletion in progress"
+	// OperationResultNotReady means that a resource is not yet ready (not an error), i.e. its pods are starting
+	OperationResultNotReady OperationResult = "resource not ready"
 	// OperationResultCRUDError means that a Create Update or Delete has failed
 	OperationResultCRUDError OperationResult = "crud error"
+	// OperationResultPruneDryRun means that an unregistered resource would have been deleted without the dry-run mode
+	OperationResultPruneDryRun OperationResult = "resource to prune (dry-run)"
+	// OperationResultPruneKept means that an unregistered resource is not deleted as it is annotated to be kept
+	OperationResultPruneKept OperationResult = "resource kept, not pruned"
 
 	///// MISC
+
+	// OperationResultReconcileTimeout means that the reconciliation has been aborted as it exceeded its maximum duration
+	OperationResultReconcileTimeout OperationResult = "reconciliation timeout exceeded"
+	// OperationResultStateActionError means that the action (handler) of a state of the reconciliation engine has returned an error
+	OperationResultStateActionError OperationResult = "state action error"
+
 	///// Alarming errors that should raise a Giveup error
 
 	// OperationResultImplementationConcern xx
@@ -122,13 +156,23 @@ type Stats interface {
 	DisplayCounters(logger logr.Logger)
 }
 
+// OpInfo is a copy of an operation's result, for an external use (display, export,...)
+type OpInfo struct {
+	Operation OperationResult
+	Resource  oktres.ResourceInfo // nil if the operation is not related to a resource
+	Err       error
+	Details   []string // i.e. the changes made by an update
+}
+
 // Results manage a list of results for a reconciler that cumulates some operation's results during execution
 type Results interface {
 	AddOp(resource oktres.ResourceInfo, result OperationResult, err error, requeueAfterSeconds uint16) error
 	AddOpSuccess(resource oktres.ResourceInfo, result OperationResult)
+	AddOpSuccessWithDetails(resource oktres.ResourceInfo, result OperationResult, details []string)
 	AddGiveupError(resource oktres.ResourceInfo, result OperationResult, err error) error
 
 	DisplayOpList(logger logr.Logger)
+	OpList() []OpInfo
 
 	// ConsolidatedError Return consolidated error
 	// Unlike ConsolidatedSigsK8S(), this method returns the ErrGiveUpReconciliation status (raised or not) and the current error of the AlarmingReason error
@@ -137,13 +181,23 @@ type Results interface {
 	// ConsolidatedSigsK8S returns:
 	//  nil if no error
 	//  It is possible to requeue with a delay (in nanoseconds). The delay returned here is computed regarding the delays reported in the Results.
-	//  In case of a same status error reported in the Results, the delay is increased of a period of time growing exponentially at each cycle (up to 6 hours)
+	//  In case of a same status error reported in the Results, the delay is increased of a period of time growing exponentially at each cycle
+	//  Each delay is computed by the requeue policy (up to 6 hours with the default policy, see UseRequeuePolicy())
 	//  In a reconciler (as expected by sigs.k8s.io), 3 outputs are possible:
 	//  - Return no error and don't requeue, same as: reconcile.Result{}, nil
 	//  - Return no error and requeue with a specified delay, same as: reconcile.Result{Requeue: true, RequeueAfter: delay}, nil
 	//  - Return an error and requeue, same as: reconcile.Result{}, err
 	ConsolidatedSigsK8S() (reconcile.Result, error)
 
+	// UseRequeuePolicy Set the policy computing the requeue delay of the operations added from now on (DefaultRequeuePolicy if nil),
+	// for the attempt-th consecutive requeued reconciliation of the CR (see RequeueAttempts)
+	UseRequeuePolicy(policy RequeuePolicy, attempt uint)
+
+	// Fork Return a new empty list of results with the same requeue policy, i.e. for a part of the reconciliation run in parallel
+	Fork() Results
+	// Merge Add the results provided (i.e. a fork) to this list, with their requeue delays, and update the consolidated result
+	Merge(results Results)
+
 	// Some counters on the reconciliation process
 	Stats
 
@@ -118,3 +118,47 @@ func TestResultsNew(t *testing.T) {
 	results.DisplayOpList(logger)
 
 }
+
+func TestResultsForkMerge(t *testing.T) {
+	myErr := errors.New("My dummy error")
+	results := NewResultList()
+	results.UseRequeuePolicy(&ExponentialRequeuePolicy{}, 2)
+	results.AddOpSuccess(nil, OperationResultCreated)
+
+	frontend, database := results.Fork(), results.Fork()
+	require.Equal(t, uint16(0), frontend.ErrorsCount(), "A fork starts empty")
+	frontend.AddOpSuccess(nil, OperationResultUpdated)
+	database.AddOp(nil, OperationResultCRUDError, myErr, 3) // Computed by the policy of the fork
+	database.AddGiveupError(nil, OperationResultImplementationConcern, myErr)
+
+	results.Merge(frontend)
+	results.Merge(database)
+	_, opsCount := results.TotalOpsCount()
+	require.Equal(t, uint16(4), opsCount)
+	require.Equal(t, uint16(2), results.ErrorsCount())
+	require.Equal(t, OperationResultUpdated, results.OpList()[1].Operation)
+	giveup, err := results.ConsolidatedError()
+	require.True(t, giveup)
+	require.Equal(t, myErr, err)
+
+	// Without the give up, the requeue delay of the fork is kept
+	results = NewResultList()
+	database = results.Fork()
+	database.UseRequeuePolicy(&ExponentialRequeuePolicy{}, 2)
+	database.AddOp(nil, OperationResultCRUDError, myErr, 3)
+	results.Merge(database)
+	rr, err := results.ConsolidatedSigsK8S()
+	require.Equal(t, 6*time.Second, rr.RequeueAfter)
+	require.Equal(t, myErr, err)
+}
+
+func TestGiveUpSentinel(t *testing.T) {
+	require.True(t, ErrGiveUpReconciliation.Reason(nil) == ErrGiveUpReconciliation, "No reason, the sentinel itself")
+
+	reason := errors.New("wrong")
+	err := ErrGiveUpReconciliation.Reason(reason)
+	require.True(t, errors.Is(err, ErrGiveUpReconciliation))
+	require.True(t, IsGiveUp(fmt.Errorf("wrapped: %w", err)))
+	require.Nil(t, ErrGiveUpReconciliation.AlarmingReasonToGiveup(), "The sentinel is left unchanged")
+	require.False(t, errors.Is(reason, ErrGiveUpReconciliation))
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okttools "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/k8sapi"
+)
+
+type myAdvancedReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR     k8sres.ConfigMap // Any K8S object can play the role of a CR here
+	dryRun bool
+	t      *testing.T
+}
+
+func (r *myAdvancedReconciler) ReconcileWithCR() {
+	res := &ConfigMapResourceStub{}
+	require.NoError(r.t, res.Init(r.Client, "ns", "registered"))
+	require.NoError(r.t, r.RegisterResource(res))
+	require.NoError(r.t, r.CreateOrUpdateAllResources(0, false))
+	r.PruneUnregisteredResources(r.dryRun, false)
+}
+
+func newConfigMap(name string, annotations map[string]string) *k8sres.ConfigMap {
+	return &k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Annotations: annotations}}
+}
+
+func TestAdvancedReconcilerPrune(t *testing.T) {
+	cmGVK := k8sres.SchemeGroupVersion.WithKind("ConfigMap")
+	inv := make(okttools.Inventory)
+	for _, name := range []string{"registered", "old", "kept"} {
+		inv.Add(okttools.NewInventoryEntry(cmGVK, types.NamespacedName{Namespace: "ns", Name: name}))
+	}
+	cr := newConfigMap("mycr", nil)
+	require.NoError(t, okttools.SetInventory(cr, inv))
+
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		cr,
+		newConfigMap("old", nil),
+		newConfigMap("kept", map[string]string{okttools.OKTKeepAnnotationName: "true"}),
+	).Build()
+
+	rec := &myAdvancedReconciler{t: t, dryRun: true}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+
+	// 1- Dry-run: nothing is deleted, the kept resource leaves the inventory
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCreated))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultPruneDryRun))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultPruneKept))
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultDeleted))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "old"}, &k8sres.ConfigMap{}))
+
+	inv, err = okttools.GetInventory(&rec.CR)
+	require.NoError(t, err)
+	require.Len(t, inv, 2, "registered and old resources remain in inventory")
+
+	// 2- Real run: the orphan is deleted
+	rec.dryRun = false
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultDeleted))
+	err = client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "old"}, &k8sres.ConfigMap{})
+	require.True(t, k8serrors.IsNotFound(err), "The orphan resource must be deleted")
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "kept"}, &k8sres.ConfigMap{}))
+
+	inv, err = okttools.GetInventory(&rec.CR)
+	require.NoError(t, err)
+	require.Len(t, inv, 1, "Only the registered resource remains in inventory")
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+)
+
+// applyClient records the server-side apply requests and emulates them, as the fake client does not support them:
+// a creation with a defaulted field, else a merge of the applied fields
+type applyClient struct {
+	k8sclient.Client
+	applied []*unstructured.Unstructured
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj k8sclient.Object, patch k8sclient.Patch, opts ...k8sclient.PatchOption) error {
+	if patch != k8sclient.Apply {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	body := &unstructured.Unstructured{}
+	if err := json.Unmarshal(data, &body.Object); err != nil {
+		return err
+	}
+	c.applied = append(c.applied, body)
+
+	u := obj.(*unstructured.Unstructured)
+	if err := c.Client.Get(ctx, k8sclient.ObjectKeyFromObject(u), u.DeepCopy()); k8serrors.IsNotFound(err) {
+		_ = unstructured.SetNestedField(u.Object, "by the server", "data", "defaulted")
+		return c.Client.Create(ctx, u)
+	}
+	return c.Client.Patch(ctx, u, k8sclient.RawPatch(types.MergePatchType, data))
+}
+
+func (c *applyClient) lastApplied(t *testing.T) map[string]string {
+	require.NotEmpty(t, c.applied)
+	data, _, err := unstructured.NestedStringMap(c.applied[len(c.applied)-1].Object, "data")
+	require.NoError(t, err)
+	return data
+}
+
+func TestAdvancedReconcilerServerSideApply(t *testing.T) {
+	dt := newDriftTest(t, oktres.DriftPolicyRevert)
+	client := &applyClient{Client: dt.client}
+	dt.rec.Client = client
+	dt.rec.EnableServerSideApply("okt", false)
+
+	// Creation
+	dt.reconcile()
+	require.Equal(t, uint16(1), dt.rec.OpsCount(okterr.OperationResultCreated))
+	require.Len(t, client.applied, 1)
+	require.Equal(t, map[string]string{"key": "value", "other": "v1"}, client.lastApplied(t))
+	require.Empty(t, client.applied[0].GetResourceVersion())
+	require.NotContains(t, client.applied[0].GetAnnotations(), okthash.OKTHashAnnotationName, "The peer fingerprint is stored after the apply")
+	require.Equal(t, "by the server", dt.configMap().Data["defaulted"])
+
+	// The defaulted field is neither a drift nor a change to apply
+	dt.reconcile()
+	dt.requireOps(0, 0)
+	require.Len(t, client.applied, 1)
+
+	// A field set by another manager is not applied with a change of the CR
+	cm := dt.configMap()
+	cm.Data["replicas"] = "3"
+	require.NoError(t, dt.client.Update(context.TODO(), cm))
+	dt.rec.other = "v2"
+	dt.reconcile()
+	require.Equal(t, uint16(1), dt.rec.OpsCount(okterr.OperationResultUpdated))
+	require.Len(t, client.applied, 2)
+	require.Equal(t, map[string]string{"key": "value", "other": "v2"}, client.lastApplied(t), "Only the fields set by the mutations are applied")
+	require.Empty(t, client.applied[1].GetResourceVersion())
+	require.Nil(t, client.applied[1].GetManagedFields())
+
+	cm = dt.configMap()
+	require.Equal(t, "v2", cm.Data["other"])
+	require.Equal(t, "3", cm.Data["replicas"])
+	require.Equal(t, "by the server", cm.Data["defaulted"])
+
+	// Stable
+	dt.reconcile()
+	dt.requireOps(0, 0)
+	require.Len(t, client.applied, 2)
+}
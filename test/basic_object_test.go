@@ -15,8 +15,10 @@
 package reconciler
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
@@ -27,7 +29,11 @@ import (
 	k8scond "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	oktclient "github.com/Orange-OpenSource/Operators-Karma-Tools/clients"
 	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
@@ -198,3 +204,113 @@ func TestBasicReconcilerStatus(t *testing.T) {
 	condition := k8scond.FindStatusCondition(cr.Status.Conditions, condType)
 	require.NotNil(t, condition, "The Status condition must exists")
 }
+
+func TestBasicReconcilerSameError(t *testing.T) {
+	rec := &myReconciler{}
+	rec.Log, rec.Client = basicobjtestGetObjs()
+
+	cr := &crtestOKTStatus{}
+	rec.Init("test3", cr, &cr.Status.Conditions)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	rec.ResetAllResults()
+	rec.AddOp(nil, okterr.OperationResultMutationSuccess, myTestError, 1)
+	rec.ManageError()
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultSameStatusError), "First occurence of the error")
+
+	// The same error, 10 minutes later
+	condition := k8scond.FindStatusCondition(cr.Status.Conditions, rec.GetManagedStatusConditionType())
+	condition.LastTransitionTime = metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	rec.ResetAllResults()
+	rec.AddOp(nil, okterr.OperationResultMutationSuccess, myTestError, 1)
+	rec.ManageError()
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultSameStatusError))
+	result, _ := rec.ConsolidatedSigsK8S()
+	require.Equal(t, 10*time.Minute, result.RequeueAfter, "Requeued after the time elapsed since the first occurence")
+}
+
+type myDeleteReconciler struct {
+	oktreconciler.BasicObject
+
+	CR k8sres.ConfigMap
+	t  *testing.T
+}
+
+func (r *myDeleteReconciler) ReconcileWithCR() {
+	for _, name := range []string{"cm1", "cm2"} {
+		res := &ConfigMapResourceStub{}
+		require.NoError(r.t, res.Init(r.Client, "other-ns", name))
+		require.NoError(r.t, r.RegisterResource(res))
+	}
+	r.DeleteAllResources(metav1.DeletePropagationBackground, true, false)
+}
+
+func TestBasicReconcilerDelete(t *testing.T) {
+	cm2 := &k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other-ns", Name: "cm2", Finalizers: []string{"test/block"}}}
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other-ns", Name: "cm1"}},
+		cm2,
+	).Build()
+
+	rec := &myDeleteReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	result, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultDeleted), "cm1 is deleted")
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultDeleteInProgress), "cm2 is blocked by its finalizer")
+	require.True(t, result.Requeue, "Requeue while a deletion is in progress")
+
+	// Unblock cm2 and reconcile again
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "other-ns", Name: "cm2"}, cm2))
+	cm2.Finalizers = nil
+	require.NoError(t, client.Update(context.TODO(), cm2))
+
+	result, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultDeleted), "Nothing left to delete")
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultDeleteInProgress))
+	require.False(t, result.Requeue)
+}
+
+// myRegistryReconciler registers a ConfigMap twice
+type myRegistryReconciler struct {
+	oktreconciler.BasicObject
+
+	CR k8sres.ConfigMap
+	t  *testing.T
+}
+
+func (r *myRegistryReconciler) ReconcileWithCR() {
+	for i := 0; i < 2; i++ {
+		res := &ConfigMapResourceStub{}
+		require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+		r.RegisterResource(res)
+	}
+	require.NotNil(r.t, r.GetRegistry().GetConfigMap("cm"))
+}
+
+func TestBasicReconcilerRegisterTwice(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	rec := &myRegistryReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultRegistrationSuccess))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultRegistrationAborted))
+	giveup, _ := rec.ConsolidatedError()
+	require.True(t, giveup)
+}
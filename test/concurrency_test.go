@@ -0,0 +1,174 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// myConcurrentReconciler creates a ConfigMap named after its CR, or gives up with the CR name as reason
+type myConcurrentReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR k8sres.ConfigMap
+
+	lock          *sync.Mutex
+	giveupReasons map[string]error // Reasons to give up seen by each request, by CR name
+}
+
+// PrepareRequest The lock and the reasons to give up are shared by the requests on purpose
+func (r *myConcurrentReconciler) PrepareRequest() {}
+
+func (r *myConcurrentReconciler) EnterInState(engine *oktengines.Stepper) {
+	switch engine.GetState() {
+	case "CRChecker":
+		if r.CR.Data["giveup"] == "true" {
+			r.AddGiveupError(nil, okterr.OperationResultCRSemanticError, errors.New(r.CR.Name))
+		}
+	case "ObjectsGetter":
+		res := &ConfigMapResourceStub{}
+		if err := res.Init(r.Client, r.CR.Namespace, r.CR.Name+"-cm"); err != nil {
+			r.AddGiveupError(nil, okterr.OperationResultImplementationConcern, err)
+			return
+		}
+		r.RegisterResource(res)
+	case "Updater":
+		r.CreateOrUpdateAllResources(0, false)
+	case "ErrorManager":
+		_, err := r.ConsolidatedError()
+		r.lock.Lock()
+		r.giveupReasons[r.CR.Name] = err
+		r.lock.Unlock()
+	}
+}
+
+// TestConcurrentReconcile Run with the race detector (go test -race) to check that the requests do not share any state
+func TestConcurrentReconcile(t *testing.T) {
+	const crCount = 20
+
+	objs := make([]k8sclient.Object, 0, crCount)
+	for i := 0; i < crCount; i++ {
+		cr := &k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: fmt.Sprint("cr", i)}}
+		if i%2 == 1 {
+			cr.Data = map[string]string{"giveup": "true"}
+		}
+		objs = append(objs, cr)
+	}
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(objs...).Build()
+
+	rec := &myConcurrentReconciler{lock: &sync.Mutex{}, giveupReasons: make(map[string]error)}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewStepper(rec))
+	require.NoError(t, rec.EnableConcurrentRequests())
+
+	var wg sync.WaitGroup
+	errs := make([]error, crCount)
+	for i := 0; i < crCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: fmt.Sprint("cr", i)}}
+			_, errs[i] = rec.Reconcile(context.TODO(), request)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < crCount; i++ {
+		crName := fmt.Sprint("cr", i)
+		require.NoError(t, errs[i])
+
+		err := client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: crName + "-cm"}, &k8sres.ConfigMap{})
+		if i%2 == 1 {
+			require.Error(t, err, "No resource created for a request which gave up")
+			require.EqualError(t, rec.giveupReasons[crName], crName, "Each request gives up for its own reason")
+			continue
+		}
+		require.NoError(t, err, "The resource of each request is created")
+		require.NotContains(t, rec.giveupReasons, crName)
+	}
+	require.Regexp(t, "^cr[0-9]+$", rec.CR.Name, "The reconciler keeps the CR of the last request served")
+}
+
+// myPointerCRReconciler holds its CR through a pointer
+type myPointerCRReconciler struct {
+	oktreconciler.BasicObject
+
+	CR      *k8sres.ConfigMap
+	crNames []string
+}
+
+func (r *myPointerCRReconciler) ReconcileWithCR() {
+	r.crNames = append(r.crNames, r.CR.Name)
+}
+
+// mySharedFieldReconciler counts its calls by CR name
+type mySharedFieldReconciler struct {
+	oktreconciler.BasicObject
+
+	CR    k8sres.ConfigMap
+	calls map[string]int
+}
+
+func (r *mySharedFieldReconciler) ReconcileWithCR() {
+	r.calls[r.CR.Name]++
+}
+
+func TestConcurrentRequestsIsolation(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+
+	// A CR held through a pointer can not be isolated, the requests are served one by one by the reconciler itself
+	rec := &myPointerCRReconciler{CR: &k8sres.ConfigMap{}}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	require.Error(t, rec.EnableConcurrentRequests())
+
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"mycr"}, rec.crNames, "The hook reads the CR fetched")
+	require.Equal(t, "mycr", rec.CR.Name)
+
+	// A field shared by the requests must be prepared by the hook
+	sf := &mySharedFieldReconciler{calls: make(map[string]int)}
+	sf.Log, _ = basicobjtestGetObjs()
+	sf.Client = client
+	sf.Init("test", &sf.CR, nil)
+	sf.SetEngine(oktengines.NewFreeStyle(sf))
+	require.EqualError(t, sf.EnableConcurrentRequests(),
+		"the field calls of the hook *reconciler.mySharedFieldReconciler would be shared by the requests, implement RequestHook")
+}
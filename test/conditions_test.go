@@ -0,0 +1,251 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8scond "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+var conditionsCRGroupVersion = schema.GroupVersion{Group: "test.okt", Version: "v1"}
+
+// conditionsCR is a CR with an observed generation in its status
+type conditionsCR struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status conditionsCRStatus `json:"status,omitempty"`
+}
+
+type conditionsCRStatus struct {
+	Phase              string             `json:"phase,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+func (c *conditionsCR) DeepCopyObject() runtime.Object {
+	cr := *c
+	c.ObjectMeta.DeepCopyInto(&cr.ObjectMeta)
+	cr.Status.Conditions = nil
+	for _, cond := range c.Status.Conditions {
+		cr.Status.Conditions = append(cr.Status.Conditions, *cond.DeepCopy())
+	}
+	return &cr
+}
+
+// myConditionsReconciler creates a ConfigMap and sets its own condition
+type myConditionsReconciler struct {
+	oktreconciler.BasicObject
+
+	CR     conditionsCR
+	t      *testing.T
+	client k8sclient.Client
+}
+
+func (r *myConditionsReconciler) ReconcileWithCR() {
+	res := &ConfigMapResourceStub{}
+	require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+	require.NoError(r.t, r.RegisterResource(res))
+	r.CreateAllResources(0, false)
+
+	require.Error(r.t, r.SetCondition("Unknown", metav1.ConditionTrue, "Test", ""), "Not declared")
+
+	// Meanwhile, the status is modified by another writer
+	cr := &conditionsCR{}
+	require.NoError(r.t, r.client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "mycr"}, cr))
+	cr.Status.Phase = "external"
+	require.NoError(r.t, r.client.Status().Update(context.TODO(), cr))
+
+	r.ManageSuccess()
+}
+
+func TestBasicReconcilerConditions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	scheme.AddKnownTypes(conditionsCRGroupVersion, &conditionsCR{})
+
+	cr := &conditionsCR{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", Generation: 3}}
+	cr.SetGroupVersionKind(conditionsCRGroupVersion.WithKind("conditionsCR"))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).Build()
+
+	rec := &myConditionsReconciler{t: t, client: client}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, &rec.CR.Status.Conditions)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	rec.DeclareConditionTypes("BackupDone")
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultStatusUpdated))
+
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, "external", cr.Status.Phase, "The status written by another is kept")
+	require.Equal(t, int64(3), cr.Status.ObservedGeneration)
+
+	expected := map[string]metav1.ConditionStatus{
+		oktreconciler.ReconcileSuccessConditionType: metav1.ConditionTrue,
+		oktreconciler.DegradedConditionType:         metav1.ConditionFalse,
+		oktreconciler.ProgressingConditionType:      metav1.ConditionTrue,
+		"BackupDone":                                metav1.ConditionUnknown,
+	}
+	require.Len(t, cr.Status.Conditions, len(expected))
+	for condType, status := range expected {
+		cond := k8scond.FindStatusCondition(cr.Status.Conditions, condType)
+		require.NotNil(t, cond, condType)
+		require.Equal(t, status, cond.Status, condType)
+		require.Equal(t, int64(3), cond.ObservedGeneration, condType)
+	}
+
+	// Nothing created: no longer progressing
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	cond := k8scond.FindStatusCondition(cr.Status.Conditions, oktreconciler.ProgressingConditionType)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, "Stable", cond.Reason)
+}
+
+// myConflictReconciler sets the phase of the CR while another writer sets a condition
+type myConflictReconciler struct {
+	oktreconciler.BasicObject
+
+	CR     conditionsCR
+	t      *testing.T
+	client k8sclient.Client
+}
+
+func (r *myConflictReconciler) ReconcileWithCR() {
+	r.CR.Status.Phase = "reconciled"
+
+	cr := &conditionsCR{}
+	require.NoError(r.t, r.client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "mycr"}, cr))
+	k8scond.SetStatusCondition(&cr.Status.Conditions, metav1.Condition{Type: "External", Status: metav1.ConditionTrue, Reason: "Test"})
+	require.NoError(r.t, r.client.Status().Update(context.TODO(), cr))
+
+	r.ManageSuccess()
+}
+
+func TestBasicReconcilerConditionsConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	scheme.AddKnownTypes(conditionsCRGroupVersion, &conditionsCR{})
+
+	cr := &conditionsCR{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", Generation: 2}}
+	cr.SetGroupVersionKind(conditionsCRGroupVersion.WithKind("conditionsCR"))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).Build()
+
+	rec := &myConflictReconciler{t: t, client: client}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, &rec.CR.Status.Conditions)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultStatusUpdated))
+
+	// Both the conditions set by the reconciler and by the other writer are kept
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, "reconciled", cr.Status.Phase)
+	require.Equal(t, int64(2), cr.Status.ObservedGeneration)
+	require.True(t, k8scond.IsStatusConditionTrue(cr.Status.Conditions, "External"))
+	require.True(t, k8scond.IsStatusConditionTrue(cr.Status.Conditions, oktreconciler.ReconcileSuccessConditionType))
+	require.Len(t, cr.Status.Conditions, 4)
+}
+
+// conflictsClient counts the conflicts returned on the CR status patches
+type conflictsClient struct {
+	k8sclient.Client
+	conflicts int
+}
+
+func (c *conflictsClient) Status() k8sclient.StatusWriter {
+	return &conflictsStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type conflictsStatusWriter struct {
+	k8sclient.StatusWriter
+	client *conflictsClient
+}
+
+func (w *conflictsStatusWriter) Patch(ctx context.Context, obj k8sclient.Object, patch k8sclient.Patch, opts ...k8sclient.PatchOption) error {
+	err := w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	if k8serrors.IsConflict(err) {
+		w.client.conflicts++
+	}
+	return err
+}
+
+// myInventoryReconciler updates the inventory of the CR before its status
+type myInventoryReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR conditionsCR
+	t  *testing.T
+}
+
+func (r *myInventoryReconciler) ReconcileWithCR() {
+	res := &ConfigMapResourceStub{}
+	require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+	require.NoError(r.t, r.RegisterResource(res))
+	r.CreateAllResources(0, false)
+	require.NoError(r.t, r.PruneUnregisteredResources(false, false))
+
+	r.ManageSuccess()
+}
+
+func TestAdvancedReconcilerPruneStatusWithoutConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	scheme.AddKnownTypes(conditionsCRGroupVersion, &conditionsCR{})
+
+	cr := &conditionsCR{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", Generation: 1}}
+	cr.SetGroupVersionKind(conditionsCRGroupVersion.WithKind("conditionsCR"))
+	client := &conflictsClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).Build()}
+
+	rec := &myInventoryReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, &rec.CR.Status.Conditions)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultStatusUpdated))
+	require.Equal(t, 0, client.conflicts, "The CR updated with its inventory is the base of the status patch")
+
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, int64(1), cr.Status.ObservedGeneration)
+}
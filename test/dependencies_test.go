@@ -0,0 +1,108 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// readyConfigMapStub is a ConfigMap reporting its readiness
+type readyConfigMapStub struct {
+	ConfigMapResourceStub
+	ready bool
+}
+
+func (r *readyConfigMapStub) IsReady() (bool, string) {
+	return r.ready, "not yet"
+}
+
+// myDependenciesReconciler registers a resource before the ones it depends on
+type myDependenciesReconciler struct {
+	oktreconciler.BasicObject
+
+	CR    k8sres.ConfigMap
+	ready *bool // Readiness of the database, shared by the requests
+	t     *testing.T
+}
+
+func (r *myDependenciesReconciler) ReconcileWithCR() {
+	app := &ConfigMapResourceStub{}
+	require.NoError(r.t, app.Init(r.Client, "ns", "app"))
+	conf := &ConfigMapResourceStub{}
+	require.NoError(r.t, conf.Init(r.Client, "ns", "conf"))
+	db := &readyConfigMapStub{ready: *r.ready}
+	require.NoError(r.t, db.Init(r.Client, "ns", "db"))
+
+	app.DependsOn(conf.Index(), db.Index())
+	require.NoError(r.t, r.RegisterResource(app))
+	require.NoError(r.t, r.RegisterResource(conf))
+	require.NoError(r.t, r.RegisterResource(db))
+
+	r.CreateAllResources(0, false)
+}
+
+func createdOps(results okterr.Results) []string {
+	ops := make([]string, 0)
+	for _, op := range results.OpList() {
+		if op.Operation == okterr.OperationResultCreated || op.Operation == okterr.OperationResultDependencyNotReady {
+			ops = append(ops, op.Resource.KindName()+" "+string(op.Operation))
+		}
+	}
+	return ops
+}
+
+func TestCreateAllResourcesDependencies(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	ready := false
+	rec := &myDependenciesReconciler{ready: &ready, t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	result, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"ConfigMap/conf resource created",
+		"ConfigMap/db resource created",
+		"ConfigMap/app resource creation waiting for its dependencies",
+	}, createdOps(rec))
+	require.Equal(t, 5*time.Second, result.RequeueAfter)
+
+	// The database is now ready
+	ready = true
+	result, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ConfigMap/app resource created"}, createdOps(rec))
+	require.False(t, result.Requeue)
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+)
+
+// defaultingClient sets a default value in the ConfigMaps written, as an API server defaulting a field
+type defaultingClient struct {
+	k8sclient.Client
+}
+
+func (c defaultingClient) setDefaults(obj k8sclient.Object) {
+	switch o := obj.(type) {
+	case *k8sres.ConfigMap:
+		if _, exists := o.Data["defaulted"]; !exists && o.Data != nil {
+			o.Data["defaulted"] = "by the server"
+		}
+	case *appsv1.Deployment:
+		if o.Spec.Strategy.Type == "" {
+			o.Spec.Strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+		}
+		if o.Spec.RevisionHistoryLimit == nil {
+			limit := int32(10)
+			o.Spec.RevisionHistoryLimit = &limit
+		}
+		for i := range o.Spec.Template.Spec.Containers {
+			if o.Spec.Template.Spec.Containers[i].ImagePullPolicy == "" {
+				o.Spec.Template.Spec.Containers[i].ImagePullPolicy = k8sres.PullIfNotPresent
+			}
+		}
+	}
+}
+
+func (c defaultingClient) Create(ctx context.Context, obj k8sclient.Object, opts ...k8sclient.CreateOption) error {
+	c.setDefaults(obj)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c defaultingClient) Update(ctx context.Context, obj k8sclient.Object, opts ...k8sclient.UpdateOption) error {
+	c.setDefaults(obj)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// driftConfigMapStub is a ConfigMap with a value brought by the CR
+type driftConfigMapStub struct {
+	mutableConfigMapStub
+	other string
+}
+
+func (r *driftConfigMapStub) MutateWithCR() (uint16, error) {
+	r.Expected.Data["key"] = "value"
+	r.Expected.Data["other"] = r.other
+	return 0, nil
+}
+
+// myDriftReconciler creates or updates a ConfigMap with the drift policy provided
+type myDriftReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR     conditionsCR
+	t      *testing.T
+	policy oktres.DriftPolicy
+	other  string
+}
+
+func (r *myDriftReconciler) ReconcileWithCR() {
+	res := &driftConfigMapStub{other: r.other}
+	require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+	res.DriftPolicy = r.policy
+	require.NoError(r.t, r.RegisterResource(res))
+	r.MutateAllResources(false)
+	r.CreateOrUpdateAllResources(0, false)
+}
+
+// driftTest reconciles the CR and edits the ConfigMap out-of-band
+type driftTest struct {
+	*testing.T
+	rec    *myDriftReconciler
+	client k8sclient.Client
+}
+
+func newDriftTest(t *testing.T, policy oktres.DriftPolicy) *driftTest {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	scheme.AddKnownTypes(conditionsCRGroupVersion, &conditionsCR{})
+
+	cr := &conditionsCR{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}}
+	cr.SetGroupVersionKind(conditionsCRGroupVersion.WithKind("conditionsCR"))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).Build()
+
+	rec := &myDriftReconciler{t: t, policy: policy, other: "v1"}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = defaultingClient{client}
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+	return &driftTest{T: t, rec: rec, client: client}
+}
+
+func (dt *driftTest) reconcile() {
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := dt.rec.Reconcile(context.TODO(), request)
+	require.NoError(dt, err)
+}
+
+func (dt *driftTest) configMap() *k8sres.ConfigMap {
+	cm := &k8sres.ConfigMap{}
+	require.NoError(dt, dt.client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "cm"}, cm))
+	return cm
+}
+
+func (dt *driftTest) edit() {
+	cm := dt.configMap()
+	cm.Data["key"] = "modified"
+	require.NoError(dt, dt.client.Update(context.TODO(), cm))
+}
+
+func (dt *driftTest) requireOps(drifted, updated uint16) {
+	require.Equal(dt, drifted, dt.rec.OpsCount(okterr.OperationResultDriftDetected), "Drifts reported")
+	require.Equal(dt, updated, dt.rec.OpsCount(okterr.OperationResultUpdated), "Updates")
+}
+
+// start creates the ConfigMap, whose defaulted fields must not be detected as a drift
+func (dt *driftTest) start() {
+	dt.reconcile()
+	require.Equal(dt, uint16(1), dt.rec.OpsCount(okterr.OperationResultCreated))
+	require.Equal(dt, "by the server", dt.configMap().Data["defaulted"])
+
+	dt.reconcile()
+	dt.requireOps(0, 0)
+}
+
+// changeSpec changes the value brought by the CR, which must be updated whatever the policy and the drifts still reported
+func (dt *driftTest) changeSpec(drifted uint16) {
+	dt.rec.other = "v2"
+	dt.reconcile()
+	dt.requireOps(drifted, 1)
+	require.Equal(dt, "v2", dt.configMap().Data["other"])
+
+	dt.reconcile()
+	dt.requireOps(0, 0)
+}
+
+func TestAdvancedReconcilerDriftRevert(t *testing.T) {
+	dt := newDriftTest(t, oktres.DriftPolicyRevert)
+	dt.start()
+
+	dt.edit()
+	dt.reconcile()
+	dt.requireOps(1, 1)
+	require.Equal(t, "value", dt.configMap().Data["key"], "The drift is reverted")
+
+	dt.reconcile()
+	dt.requireOps(0, 0)
+
+	dt.changeSpec(0)
+}
+
+func TestAdvancedReconcilerDriftReport(t *testing.T) {
+	dt := newDriftTest(t, oktres.DriftPolicyReport)
+	dt.start()
+
+	dt.edit()
+	dt.reconcile()
+	dt.requireOps(1, 0)
+	require.Equal(t, "modified", dt.configMap().Data["key"], "The drift is not reverted")
+
+	dt.reconcile()
+	dt.requireOps(1, 0)
+	require.Equal(t, "modified", dt.configMap().Data["key"], "The drift is reported while it lasts")
+
+	// The update driven by the spec overwrites the drift
+	dt.changeSpec(1)
+	require.Equal(t, "value", dt.configMap().Data["key"])
+}
+
+func TestAdvancedReconcilerDriftTolerate(t *testing.T) {
+	dt := newDriftTest(t, oktres.DriftPolicyTolerate)
+	dt.start()
+
+	dt.edit()
+	dt.reconcile()
+	dt.requireOps(1, 0)
+	require.Equal(t, "modified", dt.configMap().Data["key"], "The drift is not reverted")
+
+	dt.reconcile()
+	dt.requireOps(0, 0)
+	require.Equal(t, "modified", dt.configMap().Data["key"], "The drift is accepted")
+
+	dt.changeSpec(0)
+	require.Equal(t, "value", dt.configMap().Data["key"])
+}
+
+func TestAdvancedReconcilerDriftLegacyPeer(t *testing.T) {
+	dt := newDriftTest(t, oktres.DriftPolicyRevert)
+	dt.start()
+
+	// Peer written by a former version: only the fingerprint of the object written
+	cm := dt.configMap()
+	hash := cm.Annotations[okthash.OKTHashAnnotationName]
+	delete(cm.Annotations, okthash.OKTAppliedHashAnnotationName)
+	require.NoError(t, dt.client.Update(context.TODO(), cm))
+
+	dt.reconcile()
+	dt.requireOps(0, 0)
+	cm = dt.configMap()
+	require.Equal(t, hash, cm.Annotations[okthash.OKTHashAnnotationName], "The legacy peer is recorded")
+	require.Equal(t, hash, cm.Annotations[okthash.OKTAppliedHashAnnotationName], "The legacy peer is recorded")
+
+	dt.reconcile()
+	dt.requireOps(0, 0)
+
+	dt.edit()
+	dt.reconcile()
+	dt.requireOps(1, 1)
+	require.Equal(t, "value", dt.configMap().Data["key"], "The drift is reverted")
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// myEventsReconciler creates a ConfigMap then gives up, at each reconciliation
+type myEventsReconciler struct {
+	oktreconciler.BasicObject
+
+	CR k8sres.ConfigMap
+	t  *testing.T
+}
+
+func (r *myEventsReconciler) ReconcileWithCR() {
+	res := &ConfigMapResourceStub{}
+	require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+	require.NoError(r.t, r.RegisterResource(res))
+	r.CreateAllResources(0, false)
+	r.AddGiveupError(nil, okterr.OperationResultCRSemanticError, errors.New("wrong CR"))
+}
+
+func receivedEvents(recorder *record.FakeRecorder) []string {
+	events := make([]string, 0)
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestBasicReconcilerEvents(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	rec := &myEventsReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	rec.EnableEvents(recorder, oktreconciler.EventsOptions{OnResources: true})
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"Normal Created ConfigMap/cm: resource created", // On the CR
+		"Normal Created ConfigMap/cm: resource created", // On the ConfigMap
+		"Warning GiveUp CR is sematicaly wrong: wrong CR",
+	}, receivedEvents(recorder))
+
+	// Same give up again: not recorded twice
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Empty(t, receivedEvents(recorder))
+}
@@ -0,0 +1,217 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okttools "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/k8sapi"
+)
+
+// cleanupTask is a finalization task failing until it is told to succeed
+type cleanupTask struct {
+	name   string
+	err    error
+	calls  int
+	crName string
+}
+
+func (c *cleanupTask) GetName() string {
+	return c.name
+}
+
+func (c *cleanupTask) Finalize(ctx context.Context, cr k8sclient.Object) error {
+	c.calls++
+	c.crName = cr.GetName()
+	return c.err
+}
+
+// slowTask is a finalization task lasting until its context is done
+type slowTask struct{}
+
+func (s *slowTask) GetName() string {
+	return "slow"
+}
+
+func (s *slowTask) Finalize(ctx context.Context, cr k8sclient.Object) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// myFinalizerReconciler does nothing but the CR finalization
+type myFinalizerReconciler struct {
+	oktreconciler.BasicObject
+
+	CR   k8sres.ConfigMap
+	runs int
+}
+
+func (r *myFinalizerReconciler) ReconcileWithCR() {
+	r.runs++
+}
+
+// failingUpdateClient fails to update any object
+type failingUpdateClient struct {
+	k8sclient.Client
+}
+
+func (c failingUpdateClient) Update(ctx context.Context, obj k8sclient.Object, opts ...k8sclient.UpdateOption) error {
+	return errors.New("update refused")
+}
+
+func newFinalizerReconciler(client k8sclient.Client) *myFinalizerReconciler {
+	rec := &myFinalizerReconciler{}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	return rec
+}
+
+func TestBasicReconcilerFinalizer(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	cr := &k8sres.ConfigMap{}
+
+	// No finalizer by default
+	rec := newFinalizerReconciler(client)
+	require.Empty(t, rec.GetFinalizerName())
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultCRFinalizerAdded))
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Empty(t, cr.Finalizers)
+
+	// A finalization task requires a finalizer
+	require.Error(t, rec.SetFinalizerName("cleanup"), "Not domain-qualified")
+	cleanup := &cleanupTask{name: "cleanup", err: errors.New("unreachable")}
+	rec.RegisterFinalizer(cleanup, oktreconciler.FinalizerOptions{RetryAfter: 7})
+	require.Equal(t, okttools.OKTFinalizerName, rec.GetFinalizerName())
+
+	// The finalizer is added once
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalizerAdded))
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, []string{okttools.OKTFinalizerName}, cr.Finalizers)
+
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultCRFinalizerAdded))
+	require.Equal(t, 0, cleanup.calls, "Not being deleted")
+
+	// A failed task is retried and keeps the finalizer
+	require.NoError(t, client.Delete(context.TODO(), cr))
+	result, err := rec.Reconcile(context.TODO(), request)
+	require.Error(t, err)
+	require.Equal(t, 7*time.Second, result.RequeueAfter)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRIsFinalizing))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalizationTaskError))
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultCRFinalizerAdded), "Being deleted")
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, []string{okttools.OKTFinalizerName}, cr.Finalizers)
+
+	// Once the task succeeded, the finalizer is removed
+	cleanup.err = nil
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, 2, cleanup.calls)
+	require.Equal(t, "mycr", cleanup.crName, "The task gets the CR")
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalizationTaskDone))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalized))
+	require.True(t, k8serrors.IsNotFound(client.Get(context.TODO(), request.NamespacedName, cr)))
+}
+
+func TestBasicReconcilerFinalizerTimeout(t *testing.T) {
+	deleted := metav1.NewTime(time.Now().Add(-time.Hour))
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", DeletionTimestamp: &deleted,
+			Finalizers: []string{okttools.OKTFinalizerName}}},
+	).Build()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	cr := &k8sres.ConfigMap{}
+
+	// The task times out and is retried
+	rec := newFinalizerReconciler(client)
+	rec.RegisterFinalizer(&slowTask{}, oktreconciler.FinalizerOptions{Timeout: 10 * time.Millisecond})
+	result, err := rec.Reconcile(context.TODO(), request)
+	require.Error(t, err)
+	require.Equal(t, 5*time.Second, result.RequeueAfter)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalizationTaskTimeout))
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+
+	// Failing for too long, the task no longer blocks the finalization
+	rec = newFinalizerReconciler(client)
+	rec.RegisterFinalizer(&slowTask{}, oktreconciler.FinalizerOptions{Timeout: 10 * time.Millisecond, GiveUpAfter: time.Minute})
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalizationTaskAbandoned))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalized))
+	require.True(t, k8serrors.IsNotFound(client.Get(context.TODO(), request.NamespacedName, cr)))
+}
+
+func TestBasicReconcilerLegacyFinalizer(t *testing.T) {
+	deleted := metav1.Now()
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", DeletionTimestamp: &deleted,
+			Finalizers: []string{"mycr", "other.io/keep"}}},
+	).Build()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+
+	// The finalizer named after the CR is still removed
+	rec := newFinalizerReconciler(client)
+	require.NoError(t, rec.SetFinalizerName(""))
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalized))
+
+	cr := &k8sres.ConfigMap{}
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, []string{"other.io/keep"}, cr.Finalizers)
+}
+
+func TestBasicReconcilerFinalizerAddError(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+
+	// The reconciliation stops and is retried
+	rec := newFinalizerReconciler(failingUpdateClient{client})
+	require.NoError(t, rec.SetFinalizerName(okttools.OKTFinalizerName))
+	result, err := rec.Reconcile(context.TODO(), request)
+	require.Error(t, err)
+	require.Equal(t, 3*time.Second, result.RequeueAfter)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCRFinalizerAddError))
+	require.Equal(t, 0, rec.runs, "The engine must not run without the finalizer")
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	oktres "github.com/Orange-OpenSource/Operators-Karma-Tools/resources"
+	okthelpers "github.com/Orange-OpenSource/Operators-Karma-Tools/resources/k8s"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+)
+
+// mutableConfigMapStub is a ConfigMap whose data only depend on the CR spec
+type mutableConfigMapStub struct {
+	ConfigMapResourceStub
+}
+
+// blank assignment to verify that mutableConfigMapStub implements MutableResourceType
+var _ oktres.MutableResourceType = &mutableConfigMapStub{}
+
+func (r *mutableConfigMapStub) GetHashableRef() okthash.HashableRef {
+	hr := r.GetHashableRefHelper()
+	hr.AddUserData(&r.Expected.Data)
+	return hr
+}
+
+func (r *mutableConfigMapStub) MutateWithInitialData() error {
+	r.Expected.Data = map[string]string{}
+	return nil
+}
+
+func (r *mutableConfigMapStub) MutateWithCR() (uint16, error) {
+	r.Expected.Data["key"] = "value"
+	return 0, nil
+}
+
+// myGenerationReconciler creates or updates a ConfigMap, on the spec fast path
+type myGenerationReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR          conditionsCR
+	t           *testing.T
+	specChanged *bool
+}
+
+func (r *myGenerationReconciler) ReconcileWithCR() {
+	*r.specChanged = r.SpecChanged()
+
+	res := &mutableConfigMapStub{}
+	require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+	res.SpecOnlyMutation = true
+	require.NoError(r.t, r.RegisterResource(res))
+	r.MutateAllResources(false)
+	r.CreateOrUpdateAllResources(0, false)
+	r.ManageSuccess()
+}
+
+func TestAdvancedReconcilerSpecFastPath(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	scheme.AddKnownTypes(conditionsCRGroupVersion, &conditionsCR{})
+
+	cr := &conditionsCR{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", Generation: 3}}
+	cr.SetGroupVersionKind(conditionsCRGroupVersion.WithKind("conditionsCR"))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).Build()
+
+	rec := &myGenerationReconciler{t: t, specChanged: new(bool)}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, &rec.CR.Status.Conditions)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	rec.EnableSpecFastPath()
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	reconcileOnce := func() {
+		_, err := rec.Reconcile(context.TODO(), request)
+		require.NoError(t, err)
+	}
+
+	// Creation: the generation is observed once the reconciliation succeeds
+	reconcileOnce()
+	require.True(t, *rec.specChanged, "Generation never observed")
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCreated))
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, int64(3), cr.Status.ObservedGeneration)
+
+	// Unchanged spec: the mutation is skipped
+	reconcileOnce()
+	require.False(t, *rec.specChanged)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultMutationSkipped))
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultMutationSuccess))
+
+	// Out-of-band modification: the drift is reverted, even with an unchanged spec
+	cm := &k8sres.ConfigMap{}
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "cm"}, cm))
+	cm.Data["key"] = "modified"
+	require.NoError(t, client.Update(context.TODO(), cm))
+	reconcileOnce()
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultMutationSkipped))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultDriftDetected))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultUpdated))
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "cm"}, cm))
+	require.Equal(t, "value", cm.Data["key"])
+
+	// New generation of the CR: the resource is mutated
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	cr.Generation = 4
+	require.NoError(t, client.Update(context.TODO(), cr))
+	reconcileOnce()
+	require.True(t, *rec.specChanged)
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultMutationSkipped))
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultMutationSuccess))
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, int64(4), cr.Status.ObservedGeneration)
+}
+
+// deploymentStub is a Deployment whose replicas only depend on the CR spec
+type deploymentStub struct {
+	Expected                         appsv1.Deployment
+	okthelpers.MutableResourceObject // OKT K8S resource
+	oktres.MutationHelper
+}
+
+// blank assignment to verify that deploymentStub implements MutableResourceType
+var _ oktres.MutableResourceType = &deploymentStub{}
+
+func (r *deploymentStub) Init(client k8sclient.Client, namespace, name string) error {
+	r.Expected.APIVersion = "apps/v1"
+	r.Expected.Kind = "Deployment"
+	r.MutationHelper = &okthelpers.DefaultMutationHelper{Expected: &r.Expected}
+
+	return r.MutableResourceObject.Init(client, &r.Expected, namespace, name)
+}
+
+func (r *deploymentStub) PreMutate(scheme *runtime.Scheme) error {
+	return nil
+}
+
+func (r *deploymentStub) PostMutate(cr k8sclient.Object, scheme *runtime.Scheme) error {
+	return r.SetOwnerReference(cr, scheme)
+}
+
+func (r *deploymentStub) GetHashableRef() okthash.HashableRef {
+	hr := &okthelpers.HashableRefHelper{}
+	hr.Init(r.MutationHelper)
+	hr.AddMetaLabels()
+	hr.AddUserData(&r.Expected.Spec)
+	return hr
+}
+
+func (r *deploymentStub) MutateWithInitialData() error {
+	labels := map[string]string{"app": "myapp"}
+	r.Expected.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	r.Expected.Spec.Template.Labels = labels
+	r.Expected.Spec.Template.Spec.Containers = []k8sres.Container{{Name: "app", Image: "myapp:1.0"}}
+	return nil
+}
+
+func (r *deploymentStub) MutateWithCR() (uint16, error) {
+	replicas := int32(2)
+	r.Expected.Spec.Replicas = &replicas
+	return 0, nil
+}
+
+// myDeploymentReconciler creates or updates a Deployment on the spec fast path, without managed status conditions
+type myDeploymentReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR conditionsCR
+	t  *testing.T
+}
+
+func (r *myDeploymentReconciler) ReconcileWithCR() {
+	res := &deploymentStub{}
+	require.NoError(r.t, res.Init(r.Client, "ns", "deploy"))
+	res.SpecOnlyMutation = true
+	require.NoError(r.t, r.RegisterResource(res))
+	r.MutateAllResources(false)
+	r.CreateOrUpdateAllResources(0, false)
+	r.ManageSuccess()
+}
+
+func TestAdvancedReconcilerSpecFastPathDeployment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	scheme.AddKnownTypes(conditionsCRGroupVersion, &conditionsCR{})
+
+	cr := &conditionsCR{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr", Generation: 3}}
+	cr.SetGroupVersionKind(conditionsCRGroupVersion.WithKind("conditionsCR"))
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).Build()
+
+	rec := &myDeploymentReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = defaultingClient{client}
+	rec.Scheme = scheme
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	rec.EnableSpecFastPath()
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+
+	// Creation: the Deployment is defaulted by the server and the generation is observed without managed conditions
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultCreated))
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, cr))
+	require.Equal(t, int64(3), cr.Status.ObservedGeneration)
+
+	// Unchanged spec: the defaulted fields are not a drift, thus the mutation is skipped
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultMutationSkipped))
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultDriftDetected))
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultUpdated))
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktmetrics "github.com/Orange-OpenSource/Operators-Karma-Tools/metrics"
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// myMeasuredReconciler creates a ConfigMap with the Stepper engine
+type myMeasuredReconciler struct {
+	oktreconciler.BasicObject
+
+	CR k8sres.ConfigMap
+	t  *testing.T
+}
+
+func (r *myMeasuredReconciler) EnterInState(engine *oktengines.Stepper) {
+	switch engine.GetState() {
+	case "ObjectsGetter":
+		res := &ConfigMapResourceStub{}
+		require.NoError(r.t, res.Init(r.Client, "ns", "cm"))
+		require.NoError(r.t, r.RegisterResource(res))
+	case "Updater":
+		r.CreateAllResources(0, false)
+	}
+}
+
+// sampleCount Return the count of observations of a histogram
+func sampleCount(t *testing.T, histograms *prometheus.HistogramVec, labels ...string) uint64 {
+	metric := &dto.Metric{}
+	require.NoError(t, histograms.WithLabelValues(labels...).(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestStepperReconcileMetrics(t *testing.T) {
+	const controller = "metrics-test"
+
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	rec := &myMeasuredReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewStepper(rec))
+	rec.EnableMetrics(controller)
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(oktmetrics.OperationsTotal.WithLabelValues(controller, string(okterr.OperationResultCreated), "ConfigMap")))
+	require.Equal(t, 0.0, testutil.ToFloat64(oktmetrics.OperationErrorsTotal.WithLabelValues(controller, string(okterr.OperationResultCreated), "ConfigMap")))
+	require.Equal(t, uint64(1), sampleCount(t, oktmetrics.ReconcileDuration, controller, oktmetrics.ResultSuccess))
+	require.Equal(t, 0.0, testutil.ToFloat64(oktmetrics.RequeueDelay.WithLabelValues(controller, oktmetrics.ResultSuccess)))
+	for _, state := range []string{"CRChecker", "ObjectsGetter", "Mutator", "Updater", "SuccessManager", "End"} {
+		require.Equal(t, uint64(1), sampleCount(t, oktmetrics.StateDuration, controller, state), state)
+	}
+	require.Equal(t, uint64(0), sampleCount(t, oktmetrics.StateDuration, controller, "ErrorManager"))
+}
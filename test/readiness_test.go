@@ -0,0 +1,83 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	k8scond "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okthelpers "github.com/Orange-OpenSource/Operators-Karma-Tools/resources/k8s"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+func TestBasicReconcilerWaitReady(t *testing.T) {
+	pvc := &k8sres.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "data"},
+		Status: k8sres.PersistentVolumeClaimStatus{Phase: k8sres.ClaimPending}}
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(pvc).Build()
+
+	cr := &crtestOKTStatus{}
+
+	// A new reconciler registering the claim and a ConfigMap
+	register := func() *myReconciler {
+		rec := &myReconciler{t: t}
+		rec.Log, _ = basicobjtestGetObjs()
+		rec.Client = client
+		rec.Init("test", cr, &cr.Status.Conditions)
+		rec.SetEngine(oktengines.NewFreeStyle(rec))
+
+		res := &okthelpers.ResourceObject{}
+		require.NoError(t, res.Init(client, &k8sres.PersistentVolumeClaim{TypeMeta: metav1.TypeMeta{Kind: "PersistentVolumeClaim", APIVersion: "v1"}}, "ns", "data"))
+		require.NoError(t, rec.RegisterResource(res))
+		cm := &ConfigMapResourceStub{}
+		require.NoError(t, cm.Init(client, "ns", "missing"))
+		require.NoError(t, rec.RegisterResource(cm))
+		return rec
+	}
+
+	rec := register()
+	require.False(t, rec.WaitReady())
+	require.Equal(t, uint16(2), rec.OpsCount(okterr.OperationResultNotReady))
+	result, err := rec.ConsolidatedSigsK8S()
+	require.NoError(t, err, "Not an error")
+	require.Equal(t, 5*time.Second, result.RequeueAfter)
+	cond := k8scond.FindStatusCondition(cr.Status.Conditions, oktreconciler.ReadyConditionType)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, "PersistentVolumeClaim/data: claim not bound, ConfigMap/missing: not created", cond.Message)
+
+	// The claim is bound and the ConfigMap created
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: "data"}, pvc))
+	pvc.Status.Phase = k8sres.ClaimBound
+	require.NoError(t, client.Update(context.TODO(), pvc))
+	require.NoError(t, client.Create(context.TODO(), &k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "missing"}}))
+
+	rec = register()
+	require.True(t, rec.WaitReady())
+	require.Equal(t, uint16(0), rec.OpsCount(okterr.OperationResultNotReady))
+	cond = k8scond.FindStatusCondition(cr.Status.Conditions, oktreconciler.ReadyConditionType)
+	require.Equal(t, metav1.ConditionTrue, cond.Status)
+	require.Equal(t, "ResourcesReady", cond.Reason)
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// myRegionsReconciler creates a ConfigMap per region, with the reconciler's helpers
+type myRegionsReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR k8sres.ConfigMap
+	t  *testing.T
+}
+
+func (r *myRegionsReconciler) EnterInState(engine *oktengines.Stepper) {
+	if engine.GetState() != "ObjectsGetter" {
+		return
+	}
+	for _, name := range []string{"frontend", "database"} {
+		res := &ConfigMapResourceStub{}
+		require.NoError(r.t, res.Init(r.Client, "ns", name))
+		require.NoError(r.t, r.RegisterResource(res))
+	}
+}
+
+func (r *myRegionsReconciler) CreateConfigMap(run *oktengines.RegionRun) error {
+	for _, res := range r.GetRegisteredResources() {
+		if res.KindName() == "ConfigMap/"+run.GetName() {
+			return r.WithResults(run.Results).Create(res, 0)
+		}
+	}
+	return nil
+}
+
+func TestStepperRegionsHelpers(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	rec := &myRegionsReconciler{t: t}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	engine, err := oktengines.NewStepperBuilder().
+		AddRegions("Fork", "Join", "ObjectsGetter",
+			&oktengines.Region{Name: "frontend", Steps: []oktengines.RegionStep{{Name: "Creator", Handler: (*myRegionsReconciler).CreateConfigMap}}},
+			&oktengines.Region{Name: "database", Steps: []oktengines.RegionStep{{Name: "Creator", Handler: (*myRegionsReconciler).CreateConfigMap}}}).
+		Build(rec)
+	require.NoError(t, err)
+	rec.SetEngine(engine)
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err = rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+
+	// The creations are reported in the regions' results, then merged in the reconciler's ones
+	for _, run := range engine.GetRegions() {
+		require.Equal(t, uint16(1), run.OpsCount(okterr.OperationResultCreated), run.GetName())
+	}
+	require.Equal(t, uint16(2), rec.OpsCount(okterr.OperationResultCreated))
+	for _, name := range []string{"frontend", "database"} {
+		require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "ns", Name: name}, &k8sres.ConfigMap{}))
+	}
+}
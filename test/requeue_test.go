@@ -0,0 +1,79 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// myFailingReconciler raises a CRUD error while failing is set
+type myFailingReconciler struct {
+	oktreconciler.BasicObject
+
+	CR      k8sres.ConfigMap
+	failing *bool // Shared by the requests
+}
+
+func (r *myFailingReconciler) ReconcileWithCR() {
+	if *r.failing {
+		r.AddOp(nil, okterr.OperationResultCRUDError, errors.New("boom"), 2)
+	}
+}
+
+func TestReconcileRequeuePolicy(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	failing := true
+	rec := &myFailingReconciler{failing: &failing}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	rec.SetRequeuePolicy(&okterr.ExponentialRequeuePolicy{Max: 10 * time.Second})
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	for _, expected := range []time.Duration{2, 4, 8, 10} {
+		result, err := rec.Reconcile(context.TODO(), request)
+		require.EqualError(t, err, "boom", "The error is returned with the delay computed")
+		require.Equal(t, expected*time.Second, result.RequeueAfter)
+	}
+
+	// Back to normal, then a new error starts a new series of attempts
+	failing = false
+	result, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.False(t, result.Requeue)
+
+	failing = true
+	result, _ = rec.Reconcile(context.TODO(), request)
+	require.Equal(t, 2*time.Second, result.RequeueAfter)
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	k8sres "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
+	oktengines "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler/engines"
+	okterr "github.com/Orange-OpenSource/Operators-Karma-Tools/results"
+)
+
+// mySlowReconciler exceeds the reconciliation timeout in the ObjectsGetter state
+type mySlowReconciler struct {
+	oktreconciler.AdvancedObject
+
+	CR     k8sres.ConfigMap
+	states *[]string // States entered, shared by the requests
+}
+
+func (r *mySlowReconciler) EnterInState(engine *oktengines.Stepper) {
+	*r.states = append(*r.states, engine.GetState())
+
+	if engine.GetState() == "ObjectsGetter" {
+		<-engine.GetContext().Done()
+	}
+}
+
+func TestStepperReconcileTimeout(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	rec := &mySlowReconciler{states: &[]string{}}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewStepper(rec))
+	rec.SetReconcileTimeout(10 * time.Millisecond)
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "The request is requeued on timeout")
+	require.Equal(t, uint16(1), rec.OpsCount(okterr.OperationResultReconcileTimeout))
+	require.Equal(t, []string{"CRChecker", "ObjectsGetter"}, *rec.states, "No more step after the timeout")
+
+	giveup, _ := rec.ConsolidatedError()
+	require.False(t, giveup, "A timeout is not a reason to give up")
+}
+
+// myContextReconciler is a FreeStyle hook getting the context of the request
+type myContextReconciler struct {
+	oktreconciler.BasicObject
+
+	CR       k8sres.ConfigMap
+	deadline *time.Time // Deadline seen by the hook, shared by the requests
+}
+
+func (r *myContextReconciler) ReconcileWithCR() {}
+
+func (r *myContextReconciler) ReconcileWithContext(ctx context.Context) {
+	*r.deadline, _ = ctx.Deadline()
+}
+
+func TestFreeStyleReconcileTimeout(t *testing.T) {
+	client := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		&k8sres.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycr"}},
+	).Build()
+
+	rec := &myContextReconciler{deadline: &time.Time{}}
+	rec.Log, _ = basicobjtestGetObjs()
+	rec.Client = client
+	rec.Init("test", &rec.CR, nil)
+	rec.SetEngine(oktengines.NewFreeStyle(rec))
+	rec.SetReconcileTimeout(time.Minute)
+
+	start := time.Now()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycr"}}
+	_, err := rec.Reconcile(context.TODO(), request)
+	require.NoError(t, err)
+	require.WithinDuration(t, start.Add(time.Minute), *rec.deadline, 5*time.Second, "The hook sees the deadline of the reconciliation timeout")
+}
@@ -115,10 +115,10 @@ func (r *{{ .resType }}ResourceStub) getHelper() *okthelpers.{{ .oktResHelper }}
 
 // getResourceStubData Generates a resource stub from a template and values
 // Example for a StatefulSet in v1 resource type:
-//  - resKind is "StatefulSet"
-//  - resAPIVersion is "apps/v1" the path complement after "k8s.io/api/" API path to import
-//  - oktResHelper is "StatefulSetHelper" (a helper in resources/k8s module) or "// No helper for this resource" (a GO comment)
-//  - oktMutationHelper is set to DefaultMutationHelper (no Pre/Post Mutation, Spec part has to be defined by user)
+//   - resKind is "StatefulSet"
+//   - resAPIVersion is "apps/v1" the path complement after "k8s.io/api/" API path to import
+//   - oktResHelper is "StatefulSetHelper" (a helper in resources/k8s module) or "// No helper for this resource" (a GO comment)
+//   - oktMutationHelper is set to DefaultMutationHelper (no Pre/Post Mutation, Spec part has to be defined by user)
 func getResourceStubData(resType string, res *resourceEntry) (string, error) {
 	mutationHelper := "DefaultMutationHelper"
 	if res.mutationHelper != "" {
@@ -0,0 +1,106 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	oktsm "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/statemachine"
+)
+
+const usage = `Usage: okt-lcg <command> <graph-definition>.yaml
+
+Commands:
+  validate  Check the graph definition and report its issues (unreachable states, states which can not reach a leaf, cycles without exit,...)
+  analyze   Report the leaves and the cycles of the graph, and its issues
+`
+
+// loadModel Read and build the graph definition file
+func loadModel(file string) (*oktsm.LCGModel, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	def, err := oktsm.ParseLCGDefinition(data)
+	if err != nil {
+		return nil, err
+	}
+	return def.Build()
+}
+
+func stateNames(graph oktsm.LCGGraph, states []oktsm.LCGState) string {
+	names := make([]string, 0, len(states))
+	for _, state := range states {
+		names = append(names, graph.StateName(state))
+	}
+	return strings.Join(names, ", ")
+}
+
+// printAnalysis Print the analysis of the graph. Return false if issues are found.
+func printAnalysis(model *oktsm.LCGModel, verbose bool) bool {
+	analysis := model.Graph.Analyze(model.Initial)
+
+	if verbose {
+		fmt.Printf("Graph %q: %d states, initial state %s\n", model.Name, len(model.Graph), model.Graph.StateName(model.Initial))
+		fmt.Println("Leaves: " + stateNames(model.Graph, analysis.Leaves))
+		for _, cycle := range analysis.Cycles {
+			fmt.Println("Cycle: " + stateNames(model.Graph, cycle))
+		}
+	}
+	for _, issue := range analysis.Issues {
+		fmt.Println("Issue: " + issue.Error())
+	}
+	if len(analysis.Issues) > 0 {
+		return false
+	}
+
+	fmt.Println("The graph is valid")
+	return true
+}
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	if len(flag.Args()) != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, file := flag.Arg(0), flag.Arg(1)
+
+	var verbose bool
+	switch command {
+	case "validate":
+	case "analyze":
+		verbose = true
+	default:
+		log.Fatal(errors.New("Unknown command: " + command))
+	}
+
+	model, err := loadModel(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !printAnalysis(model, verbose) {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
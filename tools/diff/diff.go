@@ -0,0 +1,290 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+// Package diff computes a field-level diff between two versions of the hashable reference of an object
+// (see tools/hash HashableRef) to report what changed in a human-readable way, i.e. "spec.replicas: 3 -> 5".
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	k8score "k8s.io/api/core/v1"
+
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+)
+
+const (
+	// RedactedValue replaces the values of sensitive fields (Secret data)
+	RedactedValue = "<redacted>"
+	// NoneValue stands for a field which does not exist
+	NoneValue = "<none>"
+
+	// Max depth of the object's fields browsed to name the hashable reference parts
+	maxFieldsDepth = 3
+)
+
+// Change is the modification of a field
+type Change struct {
+	Path     string
+	From     interface{} // nil if the field is added
+	To       interface{} // nil if the field is removed
+	Redacted bool
+}
+
+func formatValue(value interface{}, redacted bool) string {
+	switch {
+	case value == nil:
+		return NoneValue
+	case redacted:
+		return RedactedValue
+	case value == "":
+		return `""`
+	}
+
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(value); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// String Human-readable form of the change: "path: from -> to"
+func (c Change) String() string {
+	return c.Path + ": " + formatValue(c.From, c.Redacted) + " -> " + formatValue(c.To, c.Redacted)
+}
+
+// Snapshot is a copy of the values of an object's hashable reference, indexed by their path in the object
+type Snapshot struct {
+	values   map[string]interface{}
+	redacted bool // All the values are sensitive (Secret)
+}
+
+// NewSnapshot Copy the values of the hashable reference (ref) of an object (obj).
+// Each part of the reference is named with its path in the object when it is a pointer on one of its fields (or one of its maps),
+// else it is named with its position in the reference ("ref[i]").
+// All the values of Secret objects are redacted, whatever their path (even "ref[i]"), as any part of the reference can hold their data.
+func NewSnapshot(obj interface{}, ref interface{}) (*Snapshot, error) {
+	s := &Snapshot{values: make(map[string]interface{})}
+
+	if _, isSecret := obj.(*k8score.Secret); isSecret {
+		s.redacted = true
+	}
+
+	parts, isList := ref.([]interface{})
+	if !isList {
+		parts = []interface{}{ref}
+	}
+
+	paths := fieldPaths(obj)
+	for i, part := range parts {
+		path, known := partPath(paths, part)
+		if _, exists := s.values[path]; exists || !known {
+			path = fmt.Sprintf("ref[%d]", i)
+		}
+
+		value, err := toGeneric(part)
+		if err != nil {
+			return nil, err
+		}
+		s.values[path] = value
+	}
+
+	return s, nil
+}
+
+// Diff Return the changes from this snapshot to the one provided, sorted by path
+func (s *Snapshot) Diff(to *Snapshot) []Change {
+	changes := make([]Change, 0)
+
+	for _, path := range unionKeys(s.values, to.values) {
+		s.diffValue(path, s.values[path], to.values[path], &changes)
+	}
+
+	return changes
+}
+
+// DiffStrings Same as Diff() with the changes in their human-readable form
+func (s *Snapshot) DiffStrings(to *Snapshot) []string {
+	changes := s.Diff(to)
+	result := make([]string, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, change.String())
+	}
+	return result
+}
+
+func (s *Snapshot) diffValue(path string, from, to interface{}, changes *[]Change) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+
+	// A missing map is compared as an empty one to report its entries one by one
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && to == nil || toIsMap && from == nil {
+		fromIsMap, toIsMap = true, true
+	}
+	if fromIsMap && toIsMap {
+		for _, key := range unionKeys(fromMap, toMap) {
+			if okthash.IsHashAnnotation(key) {
+				continue
+			}
+			s.diffValue(joinPath(path, key), fromMap[key], toMap[key], changes)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			var fromElem, toElem interface{}
+			if i < len(fromList) {
+				fromElem = fromList[i]
+			}
+			if i < len(toList) {
+				toElem = toList[i]
+			}
+			s.diffValue(fmt.Sprintf("%s[%d]", path, i), fromElem, toElem, changes)
+		}
+		return
+	}
+
+	*changes = append(*changes, Change{Path: path, From: from, To: to, Redacted: s.redacted})
+}
+
+// toGeneric Deep copy of a value in its generic JSON form (maps, slices and scalars)
+func toGeneric(value interface{}) (interface{}, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(b, &generic)
+	return generic, err
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// partPath Return the path of a hashable reference part (pointer on a field or map) and tells if it is known
+func partPath(paths map[uintptr]string, part interface{}) (path string, known bool) {
+	v := reflect.ValueOf(part)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if !v.IsNil() {
+			path, known = paths[v.Pointer()]
+		}
+	}
+	return path, known
+}
+
+// fieldPaths Map the address of the object's fields and maps with their JSON path. The object itself has an empty path.
+// Fields are browsed level by level, thus a field sharing its address with its first sub-field gets the shortest path.
+func fieldPaths(obj interface{}) map[uintptr]string {
+	paths := make(map[uintptr]string)
+
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return paths
+	}
+	paths[v.Pointer()] = ""
+
+	type level struct {
+		value reflect.Value
+		path  string
+	}
+	levels := []level{{value: v.Elem()}}
+
+	for depth := 0; depth < maxFieldsDepth && len(levels) > 0; depth++ {
+		next := make([]level, 0)
+		for _, l := range levels {
+			t := l.value.Type()
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				name, inline := jsonName(field)
+				if field.PkgPath != "" || name == "-" {
+					continue
+				}
+				path := l.path
+				if !inline {
+					path = joinPath(l.path, name)
+				}
+
+				fv := l.value.Field(i)
+				register(paths, fv.Addr().Pointer(), path)
+				switch fv.Kind() {
+				case reflect.Map:
+					if !fv.IsNil() {
+						register(paths, fv.Pointer(), path)
+					}
+				case reflect.Struct:
+					next = append(next, level{value: fv, path: path})
+				}
+			}
+		}
+		levels = next
+	}
+
+	return paths
+}
+
+func register(paths map[uintptr]string, addr uintptr, path string) {
+	if _, exists := paths[addr]; !exists {
+		paths[addr] = path
+	}
+}
+
+// jsonName Return the JSON name of a struct field and tells if it is inlined in its parent
+func jsonName(field reflect.StructField) (name string, inline bool) {
+	tag := field.Tag.Get("json")
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+	if name == "" {
+		if field.Anonymous {
+			return name, true
+		}
+		name = field.Name
+	}
+	return name, inline
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	okthash "github.com/Orange-OpenSource/Operators-Karma-Tools/tools/hash"
+)
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func TestDiffStatefulSet(t *testing.T) {
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sts", Labels: map[string]string{"app": "test"}},
+		Spec:       appsv1.StatefulSetSpec{Replicas: int32Ptr(3)},
+	}
+	ref := func() interface{} { return []interface{}{sts.Labels, &sts.Spec} }
+
+	before, err := NewSnapshot(sts, ref())
+	require.NoError(t, err)
+
+	sts.Spec.Replicas = int32Ptr(5)
+	sts.Labels["tier"] = "db"
+	sts.Annotations = map[string]string{okthash.OKTHashAnnotationName: "xxx"}
+	after, err := NewSnapshot(sts, ref())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"metadata.labels.tier: <none> -> db",
+		"spec.replicas: 3 -> 5",
+	}, before.DiffStrings(after))
+
+	// No change
+	require.Empty(t, after.DiffStrings(after))
+}
+
+func TestDiffHashAnnotationIgnored(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}
+	before, err := NewSnapshot(cm, &cm.ObjectMeta)
+	require.NoError(t, err)
+
+	cm.Annotations = map[string]string{okthash.OKTHashAnnotationName: "xxx"}
+	after, err := NewSnapshot(cm, &cm.ObjectMeta)
+	require.NoError(t, err)
+
+	require.Empty(t, before.Diff(after))
+}
+
+func TestDiffHashAnnotationsInReference(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Annotations: map[string]string{
+		"team":                               okthash.OKTHashAnnotationName + "-owner",
+		okthash.OKTHashAnnotationName:        "old",
+		okthash.OKTAppliedHashAnnotationName: "old",
+	}}}
+	ref := func() interface{} { return []interface{}{cm.Annotations} }
+
+	before, err := NewSnapshot(cm, ref())
+	require.NoError(t, err)
+
+	cm.Annotations = map[string]string{
+		"team":                               "db",
+		okthash.OKTHashAnnotationName:        "new",
+		okthash.OKTAppliedHashAnnotationName: "new",
+	}
+	after, err := NewSnapshot(cm, ref())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"metadata.annotations.team: " + okthash.OKTHashAnnotationName + "-owner -> db"}, before.DiffStrings(after))
+}
+
+func TestDiffSecretRedacted(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret"},
+		Data:       map[string][]byte{"password": []byte("old")},
+	}
+	ref := func() interface{} { return []interface{}{&secret.Data, &secret.Type} }
+
+	before, err := NewSnapshot(secret, ref())
+	require.NoError(t, err)
+
+	secret.Data["password"] = []byte("new")
+	secret.Type = corev1.SecretTypeOpaque
+	after, err := NewSnapshot(secret, ref())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"data.password: <redacted> -> <redacted>",
+		"type: <redacted> -> <redacted>",
+	}, before.DiffStrings(after))
+}
+
+func TestDiffSecretUnknownPartRedacted(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret"},
+		Data:       map[string][]byte{"password": []byte("old")},
+	}
+
+	// The data are copied, thus not resolved as a field of the Secret
+	before, err := NewSnapshot(secret, []interface{}{secret.Data["password"]})
+	require.NoError(t, err)
+	after, err := NewSnapshot(secret, []interface{}{[]byte("new")})
+	require.NoError(t, err)
+
+	changes := before.DiffStrings(after)
+	require.Equal(t, []string{"ref[0]: <redacted> -> <redacted>"}, changes)
+	for _, change := range changes {
+		require.NotContains(t, change, "new")
+		require.NotContains(t, change, "old")
+	}
+}
+
+func TestDiffUnknownPart(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}
+	before, err := NewSnapshot(cm, "v1")
+	require.NoError(t, err)
+	after, err := NewSnapshot(cm, "v2")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"ref[0]: v1 -> v2"}, before.DiffStrings(after))
+}
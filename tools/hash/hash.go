@@ -27,8 +27,24 @@ const (
 	// OKTHashAnnotationName is hash key to annotate a Kubernetes resource
 	// with the hash of its template.
 	OKTHashAnnotationName = "operator.k8s.orange.com/okt-hash"
+	// OKTAppliedHashAnnotationName is hash key to annotate a Kubernetes resource with the hash
+	// of its expected template as last applied by OKT, i.e. before any modification made by the Cluster (defaults,...)
+	OKTAppliedHashAnnotationName = "operator.k8s.orange.com/okt-applied-hash"
 )
 
+// hashAnnotationNames The annotations never taken under account in the hash computation
+var hashAnnotationNames = []string{OKTHashAnnotationName, OKTAppliedHashAnnotationName}
+
+// IsHashAnnotation Tells if the annotation is one of the hash annotations set by OKT, never taken under account in the hash computation
+func IsHashAnnotation(name string) bool {
+	for _, hashName := range hashAnnotationNames {
+		if name == hashName {
+			return true
+		}
+	}
+	return false
+}
+
 // SetTemplateHashAnnotation adds an annotation containing the hash of the given template into the
 // given annotations. This annotation can then be used for template comparisons.
 func SetTemplateHashAnnotation(annotations map[string]string, template interface{}) map[string]string {
@@ -62,29 +78,64 @@ func GenerateNew(obj runtime.Object, template interface{}) (bool, error) {
 		return false, err
 	}
 
-	//version := metaObj.GetResourceVersion()
-	//metaObj.SetResourceVersion("")
-
 	annotations := metaObj.GetAnnotations()
+	curHash := GetTemplateHashAnnotation(annotations)
+	newHash := computeWithoutHashes(annotations, template)
 
-	var curHash, newHash string
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[OKTHashAnnotationName] = newHash
+	metaObj.SetAnnotations(annotations)
+
+	return curHash != newHash, nil
+}
+
+// HasDrifted Tells if the object's template hash differs from the hash stored in the object's annotation, i.e. the object
+// has been modified since this hash has been stored (typically by a direct edition of the object on the Cluster).
+// Unlike GenerateNew(), the object's annotations are left unchanged.
+// It returns false if the object has no hash value, as there's no reference to compare with.
+func HasDrifted(obj runtime.Object, template interface{}) (bool, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
 
-	if annotations != nil {
-		curHash = GetTemplateHashAnnotation(annotations)
-		delete(annotations, OKTHashAnnotationName)
-		//metaObj.SetAnnotations(newAnnotations)
+	annotations := metaObj.GetAnnotations()
+	curHash, exists := annotations[OKTHashAnnotationName]
+	if !exists {
+		return false, nil
 	}
 
-	newAnnotations := SetTemplateHashAnnotation(annotations, template)
-	metaObj.SetAnnotations(newAnnotations)
-	newHash = GetTemplateHashAnnotation(metaObj.GetAnnotations())
+	return curHash != computeWithoutHashes(annotations, template), nil
+}
 
-	// Restore version
-	//metaObj.SetResourceVersion(version)
+// ComputeObject Compute the object's template hash, as GenerateNew() does, without changing the object's annotations
+func ComputeObject(obj runtime.Object, template interface{}) (string, error) {
+	metaObj, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+	return computeWithoutHashes(metaObj.GetAnnotations(), template), nil
+}
+
+// computeWithoutHashes Compute the template hash without the hash annotations, which must not be part of the computation.
+// They are restored afterwards in the object's annotations.
+func computeWithoutHashes(annotations map[string]string, template interface{}) string {
+	saved := map[string]string{}
+	for _, name := range hashAnnotationNames {
+		if value, exists := annotations[name]; exists {
+			saved[name] = value
+			delete(annotations, name)
+		}
+	}
 
-	//fmt.Printf("Hashes: %v / %v\n", curHash, newHash)
+	hash := Compute(template)
 
-	return curHash != newHash, nil
+	for name, value := range saved {
+		annotations[name] = value
+	}
+	return hash
 }
 
 // Compute writes the specified object to a hash using the spew library
@@ -125,6 +125,12 @@ func TestSetTemplateHashAnnotation(t *testing.T) {
 	require.Equal(t, expected, SetTemplateHashAnnotation(labels, spec))
 }
 
+func TestIsHashAnnotation(t *testing.T) {
+	require.True(t, IsHashAnnotation(OKTHashAnnotationName))
+	require.True(t, IsHashAnnotation(OKTAppliedHashAnnotationName))
+	require.False(t, IsHashAnnotation("operator.k8s.orange.com/okt-inventory"))
+}
+
 func TestGenerateNew(t *testing.T) {
 	pod := corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -24,8 +24,12 @@ This file is adapted from this files (under Apache 2.0 License):
 package k8suti
 
 import (
+	"fmt"
+	"strings"
+
 	//"github.com/redhat-cop/operator-utils/pkg/util/apis"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // IsBeingDeleted returns whether this object has been requested to be deleted
@@ -65,3 +69,17 @@ func RemoveFinalizer(obj metav1.Object, finalizer string) {
 	}
 	obj.SetFinalizers(f)
 }
+
+// OKTFinalizerName is the finalizer added by default by the OKT reconcilers to the Custom Resources
+const OKTFinalizerName = "operator.k8s.orange.com/okt-finalizer"
+
+// ValidateFinalizerName checks that a finalizer name is domain-qualified (i.e. "example.com/cleanup"), as expected by K8S
+func ValidateFinalizerName(finalizer string) error {
+	if !strings.Contains(finalizer, "/") {
+		return fmt.Errorf("finalizer %q is not domain-qualified", finalizer)
+	}
+	if errs := validation.IsQualifiedName(finalizer); len(errs) > 0 {
+		return fmt.Errorf("invalid finalizer %q: %s", finalizer, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package k8suti
+
+import (
+	"encoding/json"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// OKTInventoryAnnotationName is the annotation set on the Custom Resource to store the list of resources managed by OKT
+	OKTInventoryAnnotationName = "operator.k8s.orange.com/okt-inventory"
+	// OKTKeepAnnotationName is the annotation that a managed resource can carry (with the "true" value) to never be pruned
+	OKTKeepAnnotationName = "operator.k8s.orange.com/okt-keep"
+)
+
+// InventoryEntry identifies a resource managed by OKT (NGVK: NamespacedName Group Version Kind)
+type InventoryEntry struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// NewInventoryEntry Build an inventory entry from a GVK and a NamespacedName.
+// The "core" group, as used in some OKT resources apiVersion ("core/v1"), is stored as the legacy empty group.
+func NewInventoryEntry(gvk schema.GroupVersionKind, key types.NamespacedName) InventoryEntry {
+	group := gvk.Group
+	if group == "core" {
+		group = ""
+	}
+	return InventoryEntry{Group: group, Version: gvk.Version, Kind: gvk.Kind, Namespace: key.Namespace, Name: key.Name}
+}
+
+// GroupVersionKind Return the GVK of the inventory entry
+func (e InventoryEntry) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: e.Group, Version: e.Version, Kind: e.Kind}
+}
+
+// NamespacedName Return the NamespacedName of the inventory entry
+func (e InventoryEntry) NamespacedName() types.NamespacedName {
+	return types.NamespacedName{Namespace: e.Namespace, Name: e.Name}
+}
+
+// Index Return a uniq key for this entry (same composition as the NGVK key)
+func (e InventoryEntry) Index() string {
+	return e.Namespace + " " + e.Kind + "/" + e.Name + " " + e.Group + "/" + e.Version
+}
+
+// KindName Return the Kind/Name string for this entry
+func (e InventoryEntry) KindName() string {
+	return e.Kind + "/" + e.Name
+}
+
+// Inventory is a set of resources managed by OKT for a Custom Resource
+type Inventory map[string]InventoryEntry
+
+// Add Add an entry to the inventory (no duplicate)
+func (inv Inventory) Add(entry InventoryEntry) {
+	inv[entry.Index()] = entry
+}
+
+// Contains Tells if the entry is part of the inventory
+func (inv Inventory) Contains(entry InventoryEntry) bool {
+	_, exists := inv[entry.Index()]
+	return exists
+}
+
+// Difference Return the entries of this inventory which are not in the other one, sorted by index
+func (inv Inventory) Difference(other Inventory) []InventoryEntry {
+	diff := make([]InventoryEntry, 0)
+	for index, entry := range inv {
+		if _, exists := other[index]; !exists {
+			diff = append(diff, entry)
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Index() < diff[j].Index() })
+
+	return diff
+}
+
+// Equal Tells if both inventories have the same entries
+func (inv Inventory) Equal(other Inventory) bool {
+	return len(inv) == len(other) && len(inv.Difference(other)) == 0
+}
+
+// GetInventory Read the inventory stored in the object's annotations. An empty inventory is returned if none exists.
+func GetInventory(obj metav1.Object) (Inventory, error) {
+	inv := make(Inventory)
+
+	value, exists := obj.GetAnnotations()[OKTInventoryAnnotationName]
+	if !exists || value == "" {
+		return inv, nil
+	}
+
+	entries := make([]InventoryEntry, 0)
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return inv, err
+	}
+	for _, entry := range entries {
+		inv.Add(entry)
+	}
+
+	return inv, nil
+}
+
+// SetInventory Store the inventory in the object's annotations (entries are sorted to keep a stable value)
+func SetInventory(obj metav1.Object, inv Inventory) error {
+	entries := inv.Difference(Inventory{})
+
+	value, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OKTInventoryAnnotationName] = string(value)
+	obj.SetAnnotations(annotations)
+
+	return nil
+}
+
+// HasKeepAnnotation returns whether this object must be kept (never pruned)
+func HasKeepAnnotation(obj metav1.Object) bool {
+	return obj.GetAnnotations()[OKTKeepAnnotationName] == "true"
+}
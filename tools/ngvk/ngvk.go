@@ -46,6 +46,11 @@ func (ngvk NGVK) NamespacedName() types.NamespacedName {
 	return ngvk.key
 }
 
+// GVK Returns the GroupVersionKind part ("GVK") of the NGVK type
+func (ngvk NGVK) GVK() schema.GroupVersionKind {
+	return ngvk.gvk
+}
+
 func (ngvk *NGVK) set(obj client.Object) error {
 	//var err error
 
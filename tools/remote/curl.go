@@ -16,6 +16,7 @@ package tools
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -23,10 +24,11 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-var clog = logf.Log.WithName("okt_curl")
+// HTTPCurlJSON POST the data, as JSON, to the host. The request is cancelled with the context.
+// The logger is the one of the context, if any.
+func HTTPCurlJSON(ctx context.Context, hostname string, port uint16, path string, data interface{}) error {
+	clog := logf.FromContext(ctx).WithName("okt_curl")
 
-// HTTPCurlJSON xx
-func HTTPCurlJSON(hostname string, port uint16, path string, data interface{}) error {
 	payloadBytes, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -35,7 +37,7 @@ func HTTPCurlJSON(hostname string, port uint16, path string, data interface{}) e
 	url := "http://" + hostname + ":" + strconv.Itoa(int(port)) + path
 
 	//clog.Info("HTTTP Request: ", "host=", url)
-	req, err := http.NewRequest("POST", url, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
 		return err
 	}
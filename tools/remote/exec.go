@@ -16,17 +16,27 @@ package tools
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"net/http"
+	"sync"
 
 	k8score "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
 )
 
 // ExecCmd Executes a command in a container of the specified Pod
-func ExecCmd(client kubernetes.Interface, cfg *rest.Config, namespace string, pod *k8score.Pod, ctnd uint8, cmd []string) (string, string, error) {
+// When the context is done before the end of the command, the connection to the Pod is closed and the error of the context is returned
+// without the command outputs. Note that the command itself is not interrupted in the container.
+func ExecCmd(ctx context.Context, client kubernetes.Interface, cfg *rest.Config, namespace string, pod *k8score.Pod, ctnd uint8, cmd []string) (string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
 
 	req := client.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -43,18 +53,80 @@ func ExecCmd(client kubernetes.Interface, cfg *rest.Config, namespace string, po
 		TTY:       false,
 	}, scheme.ParameterCodec)
 
-	exec, err := remotecommand.NewSPDYExecutor(cfg, "POST", req.URL())
+	transport, upgrader, err := spdy.RoundTripperFor(cfg)
+	if err != nil {
+		return "", "", fmt.Errorf("could not init remote executor: %v", err)
+	}
+	conn := &ctxUpgrader{Upgrader: upgrader, ctx: ctx}
+	exec, err := remotecommand.NewSPDYExecutorForTransports(&ctxRoundTripper{RoundTripper: transport, ctx: ctx}, conn, "POST", req.URL())
 	if err != nil {
 		return "", "", fmt.Errorf("could not init remote executor: %v", err)
 	}
 
 	var stdout, stderr bytes.Buffer
 
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout: &stdout,
-		Stderr: &stderr,
-		Tty:    false,
-	})
+	done := make(chan error, 1)
+	go func() {
+		done <- exec.Stream(remotecommand.StreamOptions{
+			Stdout: &stdout,
+			Stderr: &stderr,
+			Tty:    false,
+		})
+	}()
+
+	select {
+	case err = <-done:
+		return stdout.String(), stderr.String(), err
+	case <-ctx.Done():
+		// Close the connection, thus the stream ends, and wait for it
+		conn.close()
+		<-done
+		return "", "", ctx.Err()
+	}
+}
+
+// ctxRoundTripper Send the requests with the context provided, thus the connection to the Pod is aborted when it is done
+type ctxRoundTripper struct {
+	http.RoundTripper
+	ctx context.Context
+}
+
+func (rt *ctxRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return rt.RoundTripper.RoundTrip(req.WithContext(rt.ctx))
+}
 
-	return stdout.String(), stderr.String(), err
+// ctxUpgrader Keep the connection upgraded to stream the command, to close it when the context is done
+type ctxUpgrader struct {
+	spdy.Upgrader
+	ctx context.Context
+
+	lock   sync.Mutex
+	conn   httpstream.Connection
+	closed bool
+}
+
+func (u *ctxUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	conn, err := u.Upgrader.NewConnection(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	if u.closed {
+		conn.Close()
+		return nil, u.ctx.Err()
+	}
+	u.conn = conn
+	return conn, nil
+}
+
+// close Close the connection if any, and the ones upgraded afterwards
+func (u *ctxUpgrader) close() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.closed = true
+	if u.conn != nil {
+		u.conn.Close()
+	}
 }
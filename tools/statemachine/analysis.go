@@ -0,0 +1,275 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LCGIssueKind is the kind of an issue found in a graph
+type LCGIssueKind string
+
+// Issues reported by LCGGraph.Analyze()
+const (
+	LCGIssueDuplicateName  LCGIssueKind = "duplicate name"     // Several states have the same name
+	LCGIssueUndefinedState LCGIssueKind = "undefined state"    // A child, an error child or the initial state is not defined in the graph
+	LCGIssueUnreachable    LCGIssueKind = "unreachable"        // The state can not be reached from the initial state
+	LCGIssueNoLeaf         LCGIssueKind = "no leaf reachable"  // No leaf can be reached from the state, the machine would never stop
+	LCGIssueClosedCycle    LCGIssueKind = "cycle without exit" // The states of a cycle have no child out of the cycle
+)
+
+// LCGIssue is an issue found in a graph, on a state
+type LCGIssue struct {
+	Kind  LCGIssueKind
+	State LCGState
+	Name  string // Name of the state
+	Msg   string
+}
+
+func (i LCGIssue) Error() string {
+	return fmt.Sprintf("state %q: %s: %s", i.Name, i.Kind, i.Msg)
+}
+
+// LCGGraphErrors are the issues found in a graph
+type LCGGraphErrors []LCGIssue
+
+func (e LCGGraphErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, issue := range e {
+		msgs = append(msgs, issue.Error())
+	}
+	return "invalid graph: " + strings.Join(msgs, "; ")
+}
+
+// LCGAnalysis is the result of the static analysis of a graph, from an initial state
+type LCGAnalysis struct {
+	Initial LCGState
+	Leaves  []LCGState   // The leaf states, where the machine stops
+	Cycles  [][]LCGState // The states of each cycle (strongly connected states)
+	Issues  []LCGIssue
+}
+
+// Err Return the issues as LCGGraphErrors, nil if none
+func (a *LCGAnalysis) Err() error {
+	if len(a.Issues) == 0 {
+		return nil
+	}
+	return LCGGraphErrors(a.Issues)
+}
+
+// Validate Analyze the graph from the initial state provided and return the issues found as LCGGraphErrors, nil if none
+func (g LCGGraph) Validate(initial LCGState) error {
+	return g.Analyze(initial).Err()
+}
+
+// successors Return the states the machine can enter from a state: the children and the error child, if defined in the graph
+func (g LCGGraph) successors(state LCGState) []LCGState {
+	node := g[state]
+	next := make([]LCGState, 0, len(node.Children)+1)
+	for _, child := range node.Children {
+		if _, defined := g[child]; defined {
+			next = append(next, child)
+		}
+	}
+	if node.ErrorChild != nil {
+		if _, defined := g[*node.ErrorChild]; defined && !node.Children.contains(*node.ErrorChild) {
+			next = append(next, *node.ErrorChild)
+		}
+	}
+	return next
+}
+
+// Analyze Report the leaves and the cycles of the graph, and the issues found from the initial state provided:
+// duplicate names, undefined states, unreachable states, states which can not reach a leaf and cycles without exit.
+// The guards are not considered as they are evaluated at runtime. The issues of the sub-graph of a composite state are reported
+// with the name of the composite state as prefix, i.e. "Running.Healthy". A sub-graph may have no leaf, as the parent machine can leave
+// the composite state at any time.
+func (g LCGGraph) Analyze(initial LCGState) *LCGAnalysis {
+	a := &LCGAnalysis{Initial: initial, Leaves: []LCGState{}, Cycles: [][]LCGState{}, Issues: []LCGIssue{}}
+	states := g.sortedStates()
+	addIssue := func(kind LCGIssueKind, state LCGState, format string, args ...interface{}) {
+		a.Issues = append(a.Issues, LCGIssue{Kind: kind, State: state, Name: g.StateName(state), Msg: fmt.Sprintf(format, args...)})
+	}
+
+	// Definition
+	names := make(map[string]LCGState, len(g))
+	for _, state := range states {
+		name := g[state].Name
+		if first, exists := names[name]; exists && name != "" {
+			addIssue(LCGIssueDuplicateName, state, "also the name of state %d", first)
+		} else {
+			names[name] = state
+		}
+		for _, child := range g[state].Children {
+			if _, defined := g[child]; !defined {
+				addIssue(LCGIssueUndefinedState, state, "child %s is not defined", child)
+			}
+		}
+		if errorChild := g[state].ErrorChild; errorChild != nil {
+			if _, defined := g[*errorChild]; !defined {
+				addIssue(LCGIssueUndefinedState, state, "error child %s is not defined", *errorChild)
+			}
+		}
+		if g.IsLeafNode(state) {
+			a.Leaves = append(a.Leaves, state)
+		}
+	}
+
+	// Reachability from the initial state
+	if _, defined := g[initial]; !defined {
+		addIssue(LCGIssueUndefinedState, initial, "the initial state is not defined")
+	} else {
+		reached := map[LCGState]bool{initial: true}
+		for queue := []LCGState{initial}; len(queue) > 0; queue = queue[1:] {
+			for _, next := range g.successors(queue[0]) {
+				if !reached[next] {
+					reached[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		for _, state := range states {
+			if !reached[state] {
+				addIssue(LCGIssueUnreachable, state, "not reachable from %s", g.StateName(initial))
+			}
+		}
+	}
+
+	// Leaves reachability, backward from the leaves
+	parents := make(map[LCGState][]LCGState, len(g))
+	for _, state := range states {
+		for _, next := range g.successors(state) {
+			parents[next] = append(parents[next], state)
+		}
+	}
+	reachLeaf := make(map[LCGState]bool, len(g))
+	queue := append([]LCGState{}, a.Leaves...)
+	for _, leaf := range a.Leaves {
+		reachLeaf[leaf] = true
+	}
+	for ; len(queue) > 0; queue = queue[1:] {
+		for _, parent := range parents[queue[0]] {
+			if !reachLeaf[parent] {
+				reachLeaf[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	for _, state := range states {
+		if !reachLeaf[state] {
+			addIssue(LCGIssueNoLeaf, state, "the machine would never stop")
+		}
+	}
+
+	// Cycles
+	for _, component := range g.stronglyConnectedStates(states) {
+		if len(component) == 1 && !g.successorsContain(component[0], component[0]) {
+			continue
+		}
+		a.Cycles = append(a.Cycles, component)
+		if !g.hasExit(component) {
+			names := make([]string, 0, len(component))
+			for _, state := range component {
+				names = append(names, g.StateName(state))
+			}
+			addIssue(LCGIssueClosedCycle, component[0], "no child out of the cycle %s", strings.Join(names, "->"))
+		}
+	}
+
+	// Sub-graphs of the composite states
+	for _, state := range states {
+		if composite := g[state].Composite; composite != nil {
+			for _, issue := range composite.Graph.Analyze(composite.Initial).Issues {
+				if issue.Kind == LCGIssueNoLeaf || issue.Kind == LCGIssueClosedCycle {
+					continue // The parent machine can leave the composite state at any time
+				}
+				issue.Name = g.StateName(state) + "." + issue.Name
+				a.Issues = append(a.Issues, issue)
+			}
+		}
+	}
+
+	return a
+}
+
+func (g LCGGraph) successorsContain(state, next LCGState) bool {
+	return LCGChildren(g.successors(state)).contains(next)
+}
+
+// hasExit Return true if a state of the component has a successor out of it
+func (g LCGGraph) hasExit(component []LCGState) bool {
+	in := LCGChildren(component)
+	for _, state := range component {
+		for _, next := range g.successors(state) {
+			if !in.contains(next) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stronglyConnectedStates Return the strongly connected components of the graph (Tarjan's algorithm), in ascending order of states
+func (g LCGGraph) stronglyConnectedStates(states []LCGState) [][]LCGState {
+	index := make(map[LCGState]int, len(g))
+	lowLink := make(map[LCGState]int, len(g))
+	onStack := make(map[LCGState]bool, len(g))
+	stack := []LCGState{}
+	components := [][]LCGState{}
+
+	var connect func(state LCGState)
+	connect = func(state LCGState) {
+		index[state] = len(index)
+		lowLink[state] = index[state]
+		stack = append(stack, state)
+		onStack[state] = true
+
+		for _, next := range g.successors(state) {
+			if _, visited := index[next]; !visited {
+				connect(next)
+				if lowLink[next] < lowLink[state] {
+					lowLink[state] = lowLink[next]
+				}
+			} else if onStack[next] && index[next] < lowLink[state] {
+				lowLink[state] = index[next]
+			}
+		}
+
+		if lowLink[state] == index[state] {
+			component := []LCGState{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == state {
+					break
+				}
+			}
+			sort.Slice(component, func(i, j int) bool { return component[i] < component[j] })
+			components = append(components, component)
+		}
+	}
+
+	for _, state := range states {
+		if _, visited := index[state]; !visited {
+			connect(state)
+		}
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+	return components
+}
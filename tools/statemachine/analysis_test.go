@@ -0,0 +1,69 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLCGGraphAnalyze(t *testing.T) {
+	require.NoError(t, lcGraph.Validate(Provisioning))
+
+	a := lcGraph.Analyze(Provisioning)
+	require.Equal(t, []LCGState{Deleted}, a.Leaves)
+	require.Equal(t, [][]LCGState{{Running, Upgrading, Failed}}, a.Cycles)
+	require.Empty(t, a.Issues)
+}
+
+func TestLCGGraphAnalyzeIssues(t *testing.T) {
+	const Stopping LCGState = 9
+	graph := LCGGraph{
+		Provisioning: LCGNodeInfo{Name: "Provisioning", Children: LCGChildren{Stopping, Running}},
+		Running:      LCGNodeInfo{Name: "Running", Children: LCGChildren{Upgrading}},
+		Upgrading:    LCGNodeInfo{Name: "Upgrading", Children: LCGChildren{Running}, ErrorChild: ErrorChild(Failed)},
+		Failed:       LCGNodeInfo{Name: "Failed", Children: LCGChildren{Failed}},
+		Deleted:      LCGNodeInfo{Name: "Running"},
+	}
+
+	err := graph.Validate(Provisioning)
+	var errs LCGGraphErrors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, []string{
+		`state "Provisioning": undefined state: child 9 is not defined`,
+		`state "Running": duplicate name: also the name of state 1`,
+		`state "Running": unreachable: not reachable from Provisioning`,
+		`state "Provisioning": no leaf reachable: the machine would never stop`,
+		`state "Running": no leaf reachable: the machine would never stop`,
+		`state "Upgrading": no leaf reachable: the machine would never stop`,
+		`state "Failed": no leaf reachable: the machine would never stop`,
+		`state "Failed": cycle without exit: no child out of the cycle Failed`,
+	}, issueMessages(errs))
+
+	a := graph.Analyze(Stopping)
+	require.Equal(t, []LCGState{Deleted}, a.Leaves)
+	require.Equal(t, [][]LCGState{{Running, Upgrading}, {Failed}}, a.Cycles, "The error child makes a cycle with an exit")
+	require.Equal(t, LCGIssue{Kind: LCGIssueUndefinedState, State: Stopping, Name: "9", Msg: "the initial state is not defined"}, a.Issues[2])
+}
+
+func issueMessages(errs LCGGraphErrors) []string {
+	msgs := make([]string, 0, len(errs))
+	for _, issue := range errs {
+		msgs = append(msgs, issue.Error())
+	}
+	return msgs
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+// LCGComposite is the sub-graph of a composite state, i.e. a "Running" state with "Healthy", "Degraded" and "Rebalancing" sub-states.
+// Entering a composite state enters the initial state of its own sub-machine. The events are then triggered in the sub-machine first and
+// bubble to the parent machine when they do not throw a new sub-state, or once the sub-machine has reached a leaf state.
+// Leaving a composite state leaves its current sub-state first (see LCGStateExitAction).
+type LCGComposite struct {
+	Graph   LCGGraph
+	Initial LCGState
+	// Actions called in the sub-states, the actions of the parent machine if nil (then, use distinct values for the states of both graphs)
+	Actions LCGStateAction
+}
+
+func (c *LCGComposite) actions(parent LCGStateAction) LCGStateAction {
+	if c.Actions != nil {
+		return c.Actions
+	}
+	return parent
+}
+
+// enterComposite Start the sub-machine of the composite state entered, with the same path in graph settings.
+// The error of its initial state is the error of the composite state.
+func (m *Machine) enterComposite(composite *LCGComposite) error {
+	m.sub = &Machine{Graph: composite.Graph, Actions: composite.actions(m.Actions), Now: m.Now, pathLengthLimit: m.pathLengthLimit}
+	if m.IsPathInGraphEnabled() {
+		m.sub.EnablePathInGraph()
+	}
+	_, err := m.sub.EnterState(composite.Initial)
+	return err
+}
+
+// exit Leave the current state, after the current sub-state, if any
+func (m *Machine) exit() error {
+	if m.sub != nil && !m.sub.IsOFF() {
+		if err := m.sub.exit(); err != nil {
+			return err
+		}
+	}
+	if exit, exists := m.Actions.(LCGStateExitAction); exists {
+		return exit.Exit(m.curState)
+	}
+	return nil
+}
+
+// GetSubMachine Return the sub-machine of the current state if it is a composite state, nil otherwise
+func (m *Machine) GetSubMachine() *Machine {
+	return m.sub
+}
+
+// GetStatePath Return the current state and the current states of the nested sub-machines, from the outermost
+func (m *Machine) GetStatePath() []LCGState {
+	path := []LCGState{}
+	for machine := m; machine != nil && !machine.IsOFF(); machine = machine.sub {
+		path = append(path, machine.GetState())
+	}
+	return path
+}
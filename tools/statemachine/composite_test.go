@@ -0,0 +1,154 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Sub-states, distinct from the lifecycle states as the actions are shared
+const (
+	Healthy LCGState = iota + 10
+	Degraded
+	Rebalancing
+	Draining
+	Rolling
+	Verifying
+	Upgraded
+)
+
+func compositeGraph() LCGGraph {
+	graph := LCGGraph{}
+	for state, node := range lcGraph {
+		graph[state] = node
+	}
+
+	running := graph[Running]
+	running.Composite = &LCGComposite{Initial: Healthy, Graph: LCGGraph{
+		Healthy:     LCGNodeInfo{Name: "Healthy", Children: LCGChildren{Degraded, Rebalancing}},
+		Degraded:    LCGNodeInfo{Name: "Degraded", Children: LCGChildren{Healthy}},
+		Rebalancing: LCGNodeInfo{Name: "Rebalancing", Children: LCGChildren{Healthy}},
+	}}
+	graph[Running] = running
+
+	upgrading := graph[Upgrading]
+	upgrading.Composite = &LCGComposite{Initial: Draining, Graph: LCGGraph{
+		Draining:  LCGNodeInfo{Name: "Draining", Children: LCGChildren{Rolling}},
+		Rolling:   LCGNodeInfo{Name: "Rolling", Children: LCGChildren{Verifying}},
+		Verifying: LCGNodeInfo{Name: "Verifying", Children: LCGChildren{Rolling, Upgraded}},
+		Upgraded:  LCGNodeInfo{Name: "Upgraded"},
+	}}
+	graph[Upgrading] = upgrading
+	return graph
+}
+
+// compositeNames names the states of a graph and of its sub-graphs
+func compositeNames(graph LCGGraph) LCGGraph {
+	names := LCGGraph{}
+	for state, node := range graph {
+		names[state] = node
+		if node.Composite != nil {
+			for subState, subNode := range compositeNames(node.Composite.Graph) {
+				names[subState] = subNode
+			}
+		}
+	}
+	return names
+}
+
+func TestStateMachineComposite(t *testing.T) {
+	graph := compositeGraph()
+	db := &tracedDatabase{graph: compositeNames(graph)}
+	sm := &Machine{Graph: graph, Actions: db}
+	sm.EnablePathInGraph()
+
+	sm.SetState(Provisioning)
+	entered, err := sm.EnterNextState(LCGEvents{DefaultState})
+	require.True(t, entered)
+	require.NoError(t, err)
+	require.Equal(t, []LCGState{Running, Healthy}, sm.GetStatePath(), "The initial sub-state is entered")
+
+	// Handled by the sub-machine
+	sm.EnterNextState(LCGEvents{Degraded})
+	require.Equal(t, []LCGState{Running, Degraded}, sm.GetStatePath())
+
+	// Bubbled to the machine: the sub-state is left first
+	db.trace = nil
+	sm.EnterNextState(LCGEvents{Upgrading})
+	require.Equal(t, []LCGState{Upgrading, Draining}, sm.GetStatePath())
+	require.Equal(t, []string{"exit Degraded", "exit Running", "Running->Upgrading", "enter Upgrading", "-1->Draining", "enter Draining"}, db.trace)
+
+	// Once the sub-machine reached its leaf, the events are bubbled
+	for i := 0; i < 3; i++ {
+		sm.EnterNextState(LCGEvents{DefaultState})
+	}
+	require.Equal(t, []LCGState{Upgrading}, sm.GetStatePath())
+	require.True(t, sm.GetSubMachine().IsOFF())
+	sm.EnterNextState(LCGEvents{DefaultState})
+	require.Equal(t, []LCGState{Running, Healthy}, sm.GetStatePath())
+
+	require.Equal(t, ">Provisioning>Running{>Healthy>Degraded>}>Upgrading{>Draining>Rolling>Verifying>Upgraded}>Running{>Healthy>}>", sm.GetPathInGraph())
+	history := sm.GetHistory()
+	require.Len(t, history, 4)
+	require.Len(t, history[2].Sub, 4)
+	require.Equal(t, "Verifying", history[2].Sub[2].Name)
+}
+
+func TestLifecycleComposite(t *testing.T) {
+	graph := compositeGraph()
+	actions := &tracedDatabase{graph: compositeNames(graph)}
+	status := &LifecycleStatus{}
+	step := func(events ...LCGState) {
+		b, err := json.Marshal(status)
+		require.NoError(t, err)
+		status = &LifecycleStatus{}
+		require.NoError(t, json.Unmarshal(b, status))
+		_, err = NewLifecycle(graph, Provisioning, actions, status).Step(events)
+		require.NoError(t, err)
+	}
+
+	step()
+	step(DefaultState)
+	require.Equal(t, "Running", status.State)
+	require.Equal(t, "Healthy", status.Sub.State)
+
+	step(Degraded)
+	require.Equal(t, "Running", status.State)
+	require.Equal(t, "Degraded", status.Sub.State)
+	require.Equal(t, "Healthy", status.Sub.PreviousState)
+
+	step(Upgrading)
+	require.Equal(t, "Upgrading", status.State)
+	require.Equal(t, "Draining", status.Sub.State)
+	require.Len(t, status.Sub.History, 1, "A new sub-lifecycle")
+
+	for i := 0; i < 4; i++ {
+		step(DefaultState)
+	}
+	require.Equal(t, "Running", status.State)
+	require.Equal(t, "Healthy", status.Sub.State)
+	require.Equal(t, status, status.DeepCopy())
+}
+
+func TestLCGGraphAnalyzeComposite(t *testing.T) {
+	graph := compositeGraph()
+	require.NoError(t, graph.Validate(Provisioning), "A sub-graph may have no leaf")
+
+	graph[Running].Composite.Graph[Degraded] = LCGNodeInfo{Name: "Degraded", Children: LCGChildren{Failed + 100}}
+	require.EqualError(t, graph.Validate(Provisioning), `invalid graph: state "Running.Degraded": undefined state: child 103 is not defined`)
+}
@@ -0,0 +1,238 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// LCGDefinition is the declarative definition of a graph, to load from a YAML or JSON document:
+//
+//	name: database
+//	initial: Provisioning           # The first state if not set
+//	states:
+//	  - name: Provisioning
+//	    children: [Failed, Running]  # By priority order
+//	  - name: Upgrading
+//	    children: [Failed, Running]
+//	    default: Running             # The last child if not set
+//	    errorChild: Failed           # Entered when the action entering this state fails
+//	    timeout: 15m                 # Maximum duration of the state in a Lifecycle
+//	    timeoutTo: Failed
+//	  - name: Deleted                # A leaf state
+//
+// The states are numbered in the order of their definition, from 0.
+type LCGDefinition struct {
+	Name    string               `json:"name,omitempty"`
+	Initial string               `json:"initial,omitempty"`
+	States  []LCGStateDefinition `json:"states"`
+}
+
+// LCGStateDefinition is the declarative definition of a state (see LCGDefinition)
+type LCGStateDefinition struct {
+	Name       string           `json:"name"`
+	Children   []string         `json:"children,omitempty"`
+	Default    string           `json:"default,omitempty"`
+	ErrorChild string           `json:"errorChild,omitempty"`
+	Timeout    *metav1.Duration `json:"timeout,omitempty"`
+	TimeoutTo  string           `json:"timeoutTo,omitempty"`
+}
+
+// LCGDefinitionError is an error in a graph definition, related to a state
+type LCGDefinitionError struct {
+	Index int    // Index of the state in the definition, -1 for the graph itself
+	State string // Name of the state, if any
+	Field string
+	Msg   string
+}
+
+func (e *LCGDefinitionError) Error() string {
+	if e.Index < 0 {
+		return e.Field + ": " + e.Msg
+	}
+	return fmt.Sprintf("states[%d] %q: %s: %s", e.Index, e.State, e.Field, e.Msg)
+}
+
+// LCGDefinitionErrors are all the errors of a graph definition
+type LCGDefinitionErrors []*LCGDefinitionError
+
+func (e LCGDefinitionErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid graph definition: " + strings.Join(msgs, "; ")
+}
+
+// ParseLCGDefinition Load a graph definition from a YAML or JSON document. Unknown fields are rejected.
+func ParseLCGDefinition(data []byte) (*LCGDefinition, error) {
+	def := &LCGDefinition{}
+	if err := yaml.UnmarshalStrict(data, def); err != nil {
+		return nil, fmt.Errorf("invalid graph definition: %w", err)
+	}
+	return def, nil
+}
+
+// Validate Check that the states are uniquely named and the states referenced are defined. Return LCGDefinitionErrors, if any.
+func (d *LCGDefinition) Validate() error {
+	errs := LCGDefinitionErrors{}
+	defined := make(map[string]bool, len(d.States))
+
+	if len(d.States) == 0 {
+		errs = append(errs, &LCGDefinitionError{Index: -1, Field: "states", Msg: "no state defined"})
+	}
+	for i, state := range d.States {
+		switch {
+		case state.Name == "":
+			errs = append(errs, &LCGDefinitionError{Index: i, Field: "name", Msg: "missing"})
+		case defined[state.Name]:
+			errs = append(errs, &LCGDefinitionError{Index: i, State: state.Name, Field: "name", Msg: "already defined"})
+		}
+		defined[state.Name] = true
+	}
+
+	checkRef := func(i int, field, ref string) {
+		if ref != "" && !defined[ref] {
+			errs = append(errs, &LCGDefinitionError{Index: i, State: d.States[i].Name, Field: field, Msg: fmt.Sprintf("%q is not defined", ref)})
+		}
+	}
+	if d.Initial != "" && !defined[d.Initial] {
+		errs = append(errs, &LCGDefinitionError{Index: -1, Field: "initial", Msg: fmt.Sprintf("%q is not defined", d.Initial)})
+	}
+	for i, state := range d.States {
+		children := make(map[string]bool, len(state.Children))
+		for _, child := range state.Children {
+			checkRef(i, "children", child)
+			if children[child] {
+				errs = append(errs, &LCGDefinitionError{Index: i, State: state.Name, Field: "children", Msg: fmt.Sprintf("%q listed twice", child)})
+			}
+			children[child] = true
+		}
+		if state.Default != "" && !children[state.Default] {
+			errs = append(errs, &LCGDefinitionError{Index: i, State: state.Name, Field: "default", Msg: fmt.Sprintf("%q is not a child", state.Default)})
+		}
+		checkRef(i, "errorChild", state.ErrorChild)
+		checkRef(i, "timeoutTo", state.TimeoutTo)
+		switch {
+		case state.Timeout != nil && state.Timeout.Duration <= 0:
+			errs = append(errs, &LCGDefinitionError{Index: i, State: state.Name, Field: "timeout", Msg: "must be positive"})
+		case state.Timeout != nil && state.TimeoutTo == "":
+			errs = append(errs, &LCGDefinitionError{Index: i, State: state.Name, Field: "timeoutTo", Msg: "missing for the timeout"})
+		case state.Timeout == nil && state.TimeoutTo != "":
+			errs = append(errs, &LCGDefinitionError{Index: i, State: state.Name, Field: "timeout", Msg: "missing for timeoutTo"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LCGModel is a graph built from a definition, with its initial state and the timeouts of its states
+type LCGModel struct {
+	Name     string
+	Graph    LCGGraph
+	Initial  LCGState
+	Timeouts map[LCGState]LCGTimeout
+}
+
+// Build Validate the definition and build the graph
+func (d *LCGDefinition) Build() (*LCGModel, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]LCGState, len(d.States))
+	for i, state := range d.States {
+		ids[state.Name] = LCGState(i)
+	}
+
+	m := &LCGModel{Name: d.Name, Graph: make(LCGGraph, len(d.States)), Timeouts: make(map[LCGState]LCGTimeout)}
+	if d.Initial != "" {
+		m.Initial = ids[d.Initial]
+	}
+	for i, state := range d.States {
+		node := LCGNodeInfo{Name: state.Name, Children: LCGChildren{}}
+		for _, child := range state.Children {
+			if child != state.Default {
+				node.Children = append(node.Children, ids[child])
+			}
+		}
+		if state.Default != "" {
+			node.Children = append(node.Children, ids[state.Default])
+		}
+		if state.ErrorChild != "" {
+			node.ErrorChild = ErrorChild(ids[state.ErrorChild])
+		}
+		if state.Timeout != nil {
+			m.Timeouts[LCGState(i)] = LCGTimeout{After: state.Timeout.Duration, To: ids[state.TimeoutTo]}
+		}
+		m.Graph[LCGState(i)] = node
+	}
+
+	return m, nil
+}
+
+// LCGHandler is the action of a state bound by its name (see LCGModel.Bind())
+type LCGHandler func(state LCGState) error
+
+// lcgBinding dispatches the actions to the handlers bound to the states
+type lcgBinding map[LCGState]LCGHandler
+
+// Enter LCGStateAction implementation
+func (b lcgBinding) Enter(state LCGState) error {
+	if handler, exists := b[state]; exists && handler != nil {
+		return handler(state)
+	}
+	return nil
+}
+
+// Bind Return the actions calling the handlers provided by state name. A state without handler has no action.
+// Return LCGDefinitionErrors if a handler is provided for an undefined state.
+func (m *LCGModel) Bind(handlers map[string]LCGHandler) (LCGStateAction, error) {
+	binding := make(lcgBinding, len(handlers))
+	errs := LCGDefinitionErrors{}
+	for name, handler := range handlers {
+		state, found := m.Graph.State(name)
+		if !found {
+			errs = append(errs, &LCGDefinitionError{Index: -1, Field: "handlers", Msg: fmt.Sprintf("%q is not a state of the graph", name)})
+			continue
+		}
+		binding[state] = handler
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return binding, nil
+}
+
+// NewMachine Return a machine for this graph
+func (m *LCGModel) NewMachine(actions LCGStateAction) *Machine {
+	return &Machine{Graph: m.Graph, Actions: actions}
+}
+
+// NewLifecycle Return a lifecycle for this graph, starting from its initial state, with the timeouts of its states
+func (m *LCGModel) NewLifecycle(actions LCGStateAction, status *LifecycleStatus) *Lifecycle {
+	l := NewLifecycle(m.Graph, m.Initial, actions, status)
+	for state, timeout := range m.Timeouts {
+		l.SetTimeout(state, timeout.After, timeout.To)
+	}
+	return l
+}
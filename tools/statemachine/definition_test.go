@@ -0,0 +1,143 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const lcDefinition = `
+name: database
+states:
+  - name: Provisioning
+    children: [Failed, Running]
+  - name: Running
+    children: [Deleted, Upgrading]
+  - name: Upgrading
+    children: [Running, Failed]
+    default: Running
+    errorChild: Failed
+    timeout: 10m
+    timeoutTo: Failed
+  - name: Failed
+    children: [Deleted, Running]
+  - name: Deleted
+`
+
+func TestLCGDefinition(t *testing.T) {
+	def, err := ParseLCGDefinition([]byte(lcDefinition))
+	require.NoError(t, err)
+	model, err := def.Build()
+	require.NoError(t, err)
+
+	require.Equal(t, "database", model.Name)
+	require.Equal(t, Provisioning, model.Initial)
+	require.Len(t, model.Graph, len(lcGraph))
+	for state, node := range lcGraph {
+		require.Equal(t, node.Name, model.Graph[state].Name)
+	}
+	require.Equal(t, LCGChildren{Failed, Running}, model.Graph[Upgrading].Children, "The default child is the last one")
+	require.Equal(t, Failed, *model.Graph[Upgrading].ErrorChild)
+	require.Equal(t, map[LCGState]LCGTimeout{Upgrading: {After: 10 * time.Minute, To: Failed}}, model.Timeouts)
+
+	// JSON as well
+	def, err = ParseLCGDefinition([]byte(`{"initial": "B", "states": [{"name": "A"}, {"name": "B", "children": ["A"]}]}`))
+	require.NoError(t, err)
+	model, err = def.Build()
+	require.NoError(t, err)
+	require.Equal(t, LCGState(1), model.Initial)
+
+	_, err = ParseLCGDefinition([]byte(`states: [{name: A, child: B}]`))
+	require.Error(t, err, "Unknown field")
+}
+
+func TestLCGDefinitionErrors(t *testing.T) {
+	def, err := ParseLCGDefinition([]byte(`
+initial: Start
+states:
+  - name: Running
+    children: [Stopping, Deleted]
+    default: Failed
+  - name: Running
+  - name: Upgrading
+    errorChild: Failed
+    timeout: 10m
+`))
+	require.NoError(t, err)
+
+	_, err = def.Build()
+	var errs LCGDefinitionErrors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, []string{
+		`states[1] "Running": name: already defined`,
+		`initial: "Start" is not defined`,
+		`states[0] "Running": children: "Stopping" is not defined`,
+		`states[0] "Running": children: "Deleted" is not defined`,
+		`states[0] "Running": default: "Failed" is not a child`,
+		`states[2] "Upgrading": errorChild: "Failed" is not defined`,
+		`states[2] "Upgrading": timeoutTo: missing for the timeout`,
+	}, errorMessages(errs))
+}
+
+func errorMessages(errs LCGDefinitionErrors) []string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return msgs
+}
+
+func TestLCGModelBind(t *testing.T) {
+	def, err := ParseLCGDefinition([]byte(lcDefinition))
+	require.NoError(t, err)
+	model, err := def.Build()
+	require.NoError(t, err)
+
+	_, err = model.Bind(map[string]LCGHandler{"Stopping": func(LCGState) error { return nil }})
+	require.EqualError(t, err, `invalid graph definition: handlers: "Stopping" is not a state of the graph`)
+
+	entered := []string{}
+	enter := func(state LCGState) error {
+		entered = append(entered, model.Graph.StateName(state))
+		return nil
+	}
+	actions, err := model.Bind(map[string]LCGHandler{
+		"Provisioning": enter,
+		"Running":      enter,
+		"Upgrading":    func(LCGState) error { return errors.New("upgrade failed") },
+		"Failed":       enter,
+	})
+	require.NoError(t, err)
+
+	// The handler errors are routed to the error child
+	machine := model.NewMachine(actions)
+	machine.SetState(model.Initial)
+	for _, event := range []LCGState{DefaultState, Upgrading} {
+		_, err = machine.EnterNextState(LCGEvents{event})
+	}
+	require.EqualError(t, err, "upgrade failed")
+	require.Equal(t, Failed, machine.GetState())
+	require.Equal(t, []string{"Provisioning", "Running", "Failed"}, entered)
+
+	// The timeouts are set on the lifecycle
+	lifecycle := model.NewLifecycle(actions, &LifecycleStatus{State: "Upgrading"})
+	remaining, exists := lifecycle.TimeoutIn()
+	require.True(t, exists)
+	require.LessOrEqual(t, remaining, 10*time.Minute)
+}
@@ -12,15 +12,20 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.package apis
 
-/**
-  This file is intended to replace the reconciler engine file in `reconciler/engine/stepper.go`
-  For the moment the test does not pass since a weird behaviour of the Build() function that should be fixed quickly.
+/*
+*
 
-  The Graph below is an example of LCGraph and Reconciliation states.
-**/
+	This file is intended to replace the reconciler engine file in `reconciler/engine/stepper.go`
+	For the moment the test does not pass since a weird behaviour of the Build() function that should be fixed quickly.
+
+	The Graph below is an example of LCGraph and Reconciliation states.
+
+*
+*/
 package engines
 
 import (
+	"context"
 	"fmt"
 
 	oktreconciler "github.com/Orange-OpenSource/Operators-Karma-Tools/reconciler"
@@ -65,18 +70,19 @@ func endStateHook(ctx interface{}) error {
 // It can be used to implement an idempotent Reconcile function for an Operator Controller
 //
 // Reconciliation states :
-//      CRChecker           // Check the Custom Resource received by the Controller (from the queue)
-//      ObjectsGetter       // Create all OKT resources types manipulated by this Operator, register them to the OKT Reconciler
-//      Mutator			    // Mutates OKT resources to the desired state
-//      Updater				// Create or Updates OKT resources on Cluster
-//		SuccessManager		// Is reached after the Updater state in case of success
 //
-//  Here are the debranching steps occuring on Finalization, Error, or Give-up events:
-//		CRFinalizer			// The CR is being deleted and has finalizer. This stage allows you to manage your own cleanup logic. The CR update is managed at the OKT reconciler level.
-//		ErrorManager		// Is reached after any step that raise an error different than GiveUpError. Just add an error in your Reconciler's results.
+//	     CRChecker           // Check the Custom Resource received by the Controller (from the queue)
+//	     ObjectsGetter       // Create all OKT resources types manipulated by this Operator, register them to the OKT Reconciler
+//	     Mutator			    // Mutates OKT resources to the desired state
+//	     Updater				// Create or Updates OKT resources on Cluster
+//			SuccessManager		// Is reached after the Updater state in case of success
+//
+//	 Here are the debranching steps occuring on Finalization, Error, or Give-up events:
+//			CRFinalizer			// The CR is being deleted and has finalizer. This stage allows you to manage your own cleanup logic. The CR update is managed at the OKT reconciler level.
+//			ErrorManager		// Is reached after any step that raise an error different than GiveUpError. Just add an error in your Reconciler's results.
 //
-//  Here is a special debranching step that is managed internaly by the Stepper, no need to handle it at your end
-//      GiveUpManager		// A Terminal state. Can be reached at any time/in any state (even in SuccessManager) when an unrecoverable error happen or simply when the reconciliation can't go further for the moment
+//	 Here is a special debranching step that is managed internaly by the Stepper, no need to handle it at your end
+//	     GiveUpManager		// A Terminal state. Can be reached at any time/in any state (even in SuccessManager) when an unrecoverable error happen or simply when the reconciliation can't go further for the moment
 //
 // Besides that, use the "Free style" engine if you want a full control on the reconiliation process.
 type Stepper struct {
@@ -237,6 +243,10 @@ func (smc *Stepper) SetResults(res okterr.Results) {
 	smc.Results = res
 }
 
+// SetContext Not used by this example
+func (smc *Stepper) SetContext(ctx context.Context) {
+}
+
 // Run starts from the first reconciliation step (CRChecker).
 // At each an action hook is called with a context parameter which is the Stepper engine itself.
 // It stops either if an error is returned or if the "End" state is reached
@@ -0,0 +1,170 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LCGExportOptions are the options to render a graph
+type LCGExportOptions struct {
+	Name string     // Name of the graph (DOT only)
+	Path []LCGState // A path recorded in the graph (see Machine.GetPathStates()) to overlay with visit counts, optional
+}
+
+// pathOverlay counts the visits of the states and of the transitions of a path
+type pathOverlay struct {
+	states      map[LCGState]uint
+	transitions map[[2]LCGState]uint
+}
+
+func newPathOverlay(g LCGGraph, path []LCGState) pathOverlay {
+	o := pathOverlay{states: make(map[LCGState]uint), transitions: make(map[[2]LCGState]uint)}
+	for i, state := range path {
+		o.states[state]++
+		if i > 0 && g[path[i-1]].Children.contains(state) {
+			o.transitions[[2]LCGState{path[i-1], state}]++
+		}
+	}
+	return o
+}
+
+func (t LCGChildren) contains(state LCGState) bool {
+	for _, child := range t {
+		if child == state {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedStates Return the states of the graph in ascending order
+func (g LCGGraph) sortedStates() []LCGState {
+	states := make([]LCGState, 0, len(g))
+	for state := range g {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	return states
+}
+
+func nodeID(state LCGState) string {
+	if state < 0 {
+		return fmt.Sprintf("s_%d", -state)
+	}
+	return fmt.Sprintf("s%d", state)
+}
+
+func visitsLabel(label string, visits uint) string {
+	if visits == 0 {
+		return label
+	}
+	return fmt.Sprintf("%s (x%d)", label, visits)
+}
+
+// ToDOT Render the graph in the Graphviz DOT language. The edges are labelled with the priority of the children (1 is the highest),
+// the edge to the default child is bold and the leaf states are double circles.
+// The states and transitions of the path provided, if any, are colored and labelled with their visit count.
+func (g LCGGraph) ToDOT(opts LCGExportOptions) string {
+	overlay := newPathOverlay(g, opts.Path)
+	var b strings.Builder
+
+	name := opts.Name
+	if name == "" {
+		name = "LCGGraph"
+	}
+	fmt.Fprintf(&b, "digraph %q {\n", name)
+	for _, state := range g.sortedStates() {
+		attrs := []string{fmt.Sprintf("label=%q", visitsLabel(g.StateName(state), overlay.states[state]))}
+		if g.IsLeafNode(state) {
+			attrs = append(attrs, "shape=doublecircle")
+		} else {
+			attrs = append(attrs, "shape=box")
+		}
+		if overlay.states[state] > 0 {
+			attrs = append(attrs, "style=filled", `fillcolor="lightblue"`)
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", nodeID(state), strings.Join(attrs, ", "))
+	}
+	for _, state := range g.sortedStates() {
+		children := g[state].Children
+		for i, child := range children {
+			visits := overlay.transitions[[2]LCGState{state, child}]
+			attrs := []string{fmt.Sprintf("label=%q", visitsLabel(fmt.Sprint(i+1), visits))}
+			if children.IsDefault(child) {
+				attrs = append(attrs, "style=bold")
+			}
+			if visits > 0 {
+				attrs = append(attrs, `color="blue"`)
+			}
+			fmt.Fprintf(&b, "  %s -> %s [%s];\n", nodeID(state), nodeID(child), strings.Join(attrs, ", "))
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// ToMermaid Render the graph as a Mermaid flowchart. The edges are labelled with the priority of the children (1 is the highest),
+// the edge to the default child is thick and the leaf states are circles.
+// The states and transitions of the path provided, if any, are highlighted and labelled with their visit count.
+func (g LCGGraph) ToMermaid(opts LCGExportOptions) string {
+	overlay := newPathOverlay(g, opts.Path)
+	var b strings.Builder
+
+	b.WriteString("flowchart TD\n")
+	visited := make([]string, 0)
+	for _, state := range g.sortedStates() {
+		label := visitsLabel(g.StateName(state), overlay.states[state])
+		if g.IsLeafNode(state) {
+			fmt.Fprintf(&b, "    %s((%q))\n", nodeID(state), label)
+		} else {
+			fmt.Fprintf(&b, "    %s[%q]\n", nodeID(state), label)
+		}
+		if overlay.states[state] > 0 {
+			visited = append(visited, nodeID(state))
+		}
+	}
+
+	edge := 0
+	traversed := make([]string, 0)
+	for _, state := range g.sortedStates() {
+		children := g[state].Children
+		for i, child := range children {
+			visits := overlay.transitions[[2]LCGState{state, child}]
+			arrow := "-->"
+			if children.IsDefault(child) {
+				arrow = "==>"
+			}
+			fmt.Fprintf(&b, "    %s %s|%q| %s\n", nodeID(state), arrow, visitsLabel(fmt.Sprint(i+1), visits), nodeID(child))
+			if visits > 0 {
+				traversed = append(traversed, fmt.Sprint(edge))
+			}
+			edge++
+		}
+	}
+
+	if len(visited) > 0 {
+		b.WriteString("    classDef visited fill:#add8e6\n")
+		fmt.Fprintf(&b, "    class %s visited\n", strings.Join(visited, ","))
+	}
+	if len(traversed) > 0 {
+		fmt.Fprintf(&b, "    linkStyle %s stroke:#0000ff\n", strings.Join(traversed, ","))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var exportGraph = LCGGraph{
+	Start: LCGNodeInfo{Name: "Start", Children: LCGChildren{End, Run}},
+	Run:   LCGNodeInfo{Name: "Run", Children: LCGChildren{Run, End}},
+	End:   LCGNodeInfo{Name: "End"},
+}
+
+func TestToDOT(t *testing.T) {
+	require.Equal(t, `digraph "db" {
+  s0 [label="Start", shape=box];
+  s1 [label="Run", shape=box];
+  s4 [label="End", shape=doublecircle];
+  s0 -> s4 [label="1"];
+  s0 -> s1 [label="2", style=bold];
+  s1 -> s1 [label="1"];
+  s1 -> s4 [label="2", style=bold];
+}
+`, exportGraph.ToDOT(LCGExportOptions{Name: "db"}))
+
+	sm := &Machine{Graph: exportGraph}
+	sm.EnablePathInGraph()
+	sm.SetState(Start)
+	sm.EnterNextState(LCGEvents{Run})
+	sm.EnterNextState(LCGEvents{Run})
+	sm.EnterNextState(LCGEvents{Run})
+	sm.EnterNextState(LCGEvents{DefaultState})
+	require.Equal(t, []LCGState{Start, Run, Run, Run, End}, sm.GetPathStates())
+
+	require.Equal(t, `digraph "LCGGraph" {
+  s0 [label="Start (x1)", shape=box, style=filled, fillcolor="lightblue"];
+  s1 [label="Run (x3)", shape=box, style=filled, fillcolor="lightblue"];
+  s4 [label="End (x1)", shape=doublecircle, style=filled, fillcolor="lightblue"];
+  s0 -> s4 [label="1"];
+  s0 -> s1 [label="2 (x1)", style=bold, color="blue"];
+  s1 -> s1 [label="1 (x2)", color="blue"];
+  s1 -> s4 [label="2 (x1)", style=bold, color="blue"];
+}
+`, exportGraph.ToDOT(LCGExportOptions{Path: sm.GetPathStates()}))
+}
+
+func TestToMermaid(t *testing.T) {
+	require.Equal(t, `flowchart TD
+    s0["Start"]
+    s1["Run"]
+    s4(("End"))
+    s0 -->|"1"| s4
+    s0 ==>|"2"| s1
+    s1 -->|"1"| s1
+    s1 ==>|"2"| s4
+`, exportGraph.ToMermaid(LCGExportOptions{}))
+
+	require.Equal(t, `flowchart TD
+    s0["Start (x1)"]
+    s1["Run (x1)"]
+    s4(("End"))
+    s0 -->|"1"| s4
+    s0 ==>|"2 (x1)"| s1
+    s1 -->|"1"| s1
+    s1 ==>|"2"| s4
+    classDef visited fill:#add8e6
+    class s0,s1 visited
+    linkStyle 1 stroke:#0000ff
+`, exportGraph.ToMermaid(LCGExportOptions{Path: []LCGState{Start, Run}}))
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"strconv"
+	"time"
+)
+
+// LCGStep is a step of the path in graph: a state entered by the machine
+type LCGStep struct {
+	State     LCGState
+	Name      string
+	EnteredAt time.Time
+	Duration  time.Duration // Time spent in the state, 0 while the machine is in it
+	Events    LCGEvents     // Events which triggered the transition, nil when the state is set (see SetState()) or entered on error (see ErrorChild)
+	Err       error         // Error of the action entering the state
+	Sub       []LCGStep     // Steps of the sub-machine of a composite state
+}
+
+// lcgHistory is a ring buffer of the last steps of a path in graph
+type lcgHistory struct {
+	steps []LCGStep
+	first int // Index of the oldest step
+	count int
+	open  bool // The duration of the last step is not yet known
+}
+
+func newLCGHistory(limit uint) *lcgHistory {
+	return &lcgHistory{steps: make([]LCGStep, limit)}
+}
+
+// last Return the last step recorded, nil if none
+func (h *lcgHistory) last() *LCGStep {
+	if h == nil || h.count == 0 {
+		return nil
+	}
+	return &h.steps[(h.first+h.count-1)%len(h.steps)]
+}
+
+// enter Record a new step, the oldest one is overwritten when the history is full
+func (h *lcgHistory) enter(step LCGStep) {
+	if h == nil {
+		return
+	}
+	h.close(step.EnteredAt)
+
+	if h.count < len(h.steps) {
+		h.count++
+	} else {
+		h.first = (h.first + 1) % len(h.steps)
+	}
+	*h.last() = step
+	h.open = true
+}
+
+// close Set the duration of the last step
+func (h *lcgHistory) close(now time.Time) {
+	if last := h.last(); last != nil && h.open {
+		last.Duration = now.Sub(last.EnteredAt)
+		h.open = false
+	}
+}
+
+// Steps Return the steps recorded, from the oldest
+func (h *lcgHistory) Steps() []LCGStep {
+	if h == nil {
+		return []LCGStep{}
+	}
+	steps := make([]LCGStep, 0, h.count)
+	for i := 0; i < h.count; i++ {
+		steps = append(steps, h.steps[(h.first+i)%len(h.steps)])
+	}
+	return steps
+}
+
+// resize Change the maximum steps recorded, the last ones are kept
+func (h *lcgHistory) resize(limit uint) *lcgHistory {
+	resized := newLCGHistory(limit)
+	steps := h.Steps()
+	if len(steps) > int(limit) {
+		steps = steps[len(steps)-int(limit):]
+	}
+	for _, step := range steps {
+		resized.enter(step)
+	}
+	resized.open = h.open
+	return resized
+}
+
+// renderPath Render the steps of a path in graph as a string where the loops between 2 states are compacted, i.e. ">Start>(Run<<>>Servicing)x3>Stopping>End".
+// The path of the sub-machine of a composite state is rendered in braces: ">Start>Run{>Healthy>Degraded>}>Stopping>End"
+func renderPath(g LCGGraph, steps []LCGStep) string {
+	path := []string{">"}
+	var loopsCount uint
+	prevState, curState := DefaultState, DefaultState // The machine is OFF
+
+	for _, step := range steps {
+		state := step.State
+		var loopPathLength uint
+		if prevState == curState {
+			prevState = DefaultState
+		} else {
+			// The length of the path in the loop is 2 nodes. Not compacted with a composite state, to render each path of its sub-machine.
+			if prevState == state && g[state].Composite == nil && g[curState].Composite == nil {
+				loopPathLength = 2
+			}
+			prevState = curState
+		}
+		curState = state
+
+		stateName := g.StateName(state)
+		if composite := g[state].Composite; composite != nil && len(step.Sub) > 0 {
+			stateName += "{" + renderPath(composite.Graph, step.Sub) + "}"
+		}
+		switch {
+		case g.IsLeafNode(state):
+			path = append(path, stateName)
+			loopsCount = 0
+			prevState, curState = DefaultState, DefaultState
+		case loopPathLength == 0: // There's no loop
+			loopsCount = 0
+			path = append(path, stateName, ">")
+		default:
+			loopsCount++
+			pathLen := len(path)
+			path[pathLen-int(loopPathLength*2+1)] = ">("
+			path[pathLen-3] = "<<>>"
+			count := float32(loopsCount)/2.0 + 1.0
+			if float32(int(count)) == count {
+				path[pathLen-1] = ")x" + strconv.Itoa(int(count)) + ">"
+			} else {
+				path[pathLen-1] = ")x" + strconv.Itoa(int(count)) + ">" + stateName + ">"
+			}
+		}
+	}
+
+	rendered := ""
+	for _, p := range path {
+		rendered += p
+	}
+	return rendered
+}
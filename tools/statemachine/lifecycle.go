@@ -0,0 +1,284 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultLifecycleHistoryLimit is the count of transitions kept in the history of a lifecycle by default
+const DefaultLifecycleHistoryLimit = 10
+
+// LifecycleTransition is a transition of a lifecycle from a state to another one
+type LifecycleTransition struct {
+	From   string      `json:"from,omitempty"` // Empty for the initial state
+	To     string      `json:"to"`
+	At     metav1.Time `json:"at"`
+	Reason string      `json:"reason,omitempty"` // i.e. the timeout of the previous state
+}
+
+// LifecycleStatus is the state of a Lifecycle machine persisted between the reconciliations. Add it to your CR status:
+//
+//	type MyAppStatus struct {
+//	    Conditions []metav1.Condition       `json:"conditions,omitempty"`
+//	    Lifecycle  oktsm.LifecycleStatus     `json:"lifecycle,omitempty"`
+//	}
+//
+// The states are stored by their name, thus the LCGState values of a graph can change between two versions of an operator.
+type LifecycleStatus struct {
+	State         string                `json:"state,omitempty"`
+	PreviousState string                `json:"previousState,omitempty"`
+	EnteredAt     metav1.Time           `json:"enteredAt,omitempty"`
+	History       []LifecycleTransition `json:"history,omitempty"` // The last transitions, the most recent at the end
+	Sub           *LifecycleStatus      `json:"sub,omitempty"`     // The status of the sub-lifecycle of a composite state (see LCGComposite)
+}
+
+// DeepCopyInto copies the receiver into out (as expected by the CR code generated by controller-gen)
+func (in *LifecycleStatus) DeepCopyInto(out *LifecycleStatus) {
+	*out = *in
+	in.EnteredAt.DeepCopyInto(&out.EnteredAt)
+	if in.History != nil {
+		out.History = make([]LifecycleTransition, len(in.History))
+		for i := range in.History {
+			out.History[i] = in.History[i]
+			in.History[i].At.DeepCopyInto(&out.History[i].At)
+		}
+	}
+	out.Sub = in.Sub.DeepCopy()
+}
+
+// DeepCopy Return a copy of the receiver
+func (in *LifecycleStatus) DeepCopy() *LifecycleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// LCGTimeout routes a lifecycle to a state (typically an error state) when it stays too long in another one
+type LCGTimeout struct {
+	After time.Duration
+	To    LCGState
+}
+
+// Lifecycle is a state machine whose state is persisted in a LifecycleStatus (in the CR status) to manage the lifecycle of an
+// application over many reconciliations: at each reconciliation it resumes from the persisted state, then it enters the next state
+// triggered by the events collected during the reconciliation (see LCGGraph.NextState()), if any. Otherwise it stays in the current
+// state, i.e. an "Upgrading" state waiting for the pods to be ready, up to the timeout of this state, if any.
+// The CR status has to be updated by the reconciler to persist the changes (see BasicObject.ManageSuccess()).
+/* Example:
+
+lifecycle := oktsm.NewLifecycle(graph, Provisioning, db, &r.CR.Status.Lifecycle)
+lifecycle.SetTimeout(Upgrading, 15*time.Minute, Failed)
+
+events := oktsm.LCGEvents{}
+if podsReady {
+	events = append(events, Running)
+}
+if _, err := lifecycle.Step(events); err != nil { ... }
+if remaining, exists := lifecycle.TimeoutIn(); exists { ... requeue after remaining }
+*/
+type Lifecycle struct {
+	Graph        LCGGraph
+	Initial      LCGState       // The state entered when the status is empty
+	Actions      LCGStateAction // Called when entering a state, optional
+	Status       *LifecycleStatus
+	HistoryLimit int              // DefaultLifecycleHistoryLimit if 0
+	Now          func() time.Time // time.Now if nil
+
+	timeouts map[LCGState]LCGTimeout
+}
+
+// NewLifecycle Return a lifecycle machine for the graph provided, persisted in the status provided
+func NewLifecycle(graph LCGGraph, initial LCGState, actions LCGStateAction, status *LifecycleStatus) *Lifecycle {
+	return &Lifecycle{Graph: graph, Initial: initial, Actions: actions, Status: status}
+}
+
+// SetTimeout Set the maximum duration of a state, after which the lifecycle is routed to the state "to"
+func (l *Lifecycle) SetTimeout(state LCGState, after time.Duration, to LCGState) {
+	if l.timeouts == nil {
+		l.timeouts = make(map[LCGState]LCGTimeout)
+	}
+	l.timeouts[state] = LCGTimeout{After: after, To: to}
+}
+
+func (l *Lifecycle) now() time.Time {
+	if l.Now == nil {
+		return time.Now()
+	}
+	return l.Now()
+}
+
+// GetState Return the current state as persisted in the status. Not found if the lifecycle has not started yet.
+func (l *Lifecycle) GetState() (state LCGState, found bool, err error) {
+	if l.Status.State == "" {
+		return DefaultState, false, nil
+	}
+	state, found = l.Graph.State(l.Status.State)
+	if !found {
+		return DefaultState, false, fmt.Errorf("the lifecycle state %q persisted is not a state of the graph", l.Status.State)
+	}
+	return state, true, nil
+}
+
+// TimeInState Return the time elapsed since the current state has been entered
+func (l *Lifecycle) TimeInState() time.Duration {
+	if l.Status.EnteredAt.IsZero() {
+		return 0
+	}
+	return l.now().Sub(l.Status.EnteredAt.Time)
+}
+
+// TimeoutIn Return the time remaining before the timeout of the current state, if it has one
+func (l *Lifecycle) TimeoutIn() (remaining time.Duration, exists bool) {
+	state, found, _ := l.GetState()
+	timeout, exists := l.timeouts[state]
+	if !found || !exists {
+		return 0, false
+	}
+	remaining = timeout.After - l.TimeInState()
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// Step Resume the lifecycle from its persisted state and enter the next state if any:
+//   - the initial state if the lifecycle has not started yet
+//   - the timeout's state if the current state has exceeded its timeout
+//   - else the state triggered by the events and allowed by its guard (see LCGGraph.NextState()), if any
+//
+// As for a Machine, the current state is left (Exit), the transition is notified (OnTransition) and the new state is entered. If the
+// action entering the new state fails, its error child, if any, is entered in turn. The status is updated with each state entered and
+// the first error is returned. A lifecycle which reached a leaf state does not change anymore.
+// In a composite state, the events are first triggered in its sub-lifecycle, persisted in the Sub status (see LCGComposite).
+// The timeouts apply to the states of the graph of the lifecycle only, not to the sub-states.
+func (l *Lifecycle) Step(events LCGEvents) (entered bool, err error) {
+	state, started, err := l.GetState()
+	if err != nil {
+		return false, err
+	}
+	if !started {
+		return true, l.transitionTo(DefaultState, l.Initial, "")
+	}
+
+	next, reason := DefaultState, ""
+	if timeout, exists := l.timeouts[state]; exists && l.TimeInState() >= timeout.After {
+		next, reason = timeout.To, fmt.Sprintf("timeout of %s exceeded in %s", timeout.After, l.Graph.StateName(state))
+	} else {
+		if sub := l.subLifecycle(state); sub != nil {
+			if entered, err = sub.Step(events); entered || err != nil {
+				return entered, err
+			}
+		}
+		if next, entered = l.Graph.NextState(state, events); !entered {
+			return false, nil
+		}
+	}
+
+	if err = l.exit(state); err != nil {
+		return false, err
+	}
+	return true, l.transitionTo(state, next, reason)
+}
+
+// subLifecycle Return the sub-lifecycle of a composite state, persisted in the Sub status. Nil if the state is not composite.
+func (l *Lifecycle) subLifecycle(state LCGState) *Lifecycle {
+	composite := l.Graph[state].Composite
+	if composite == nil {
+		return nil
+	}
+	if l.Status.Sub == nil {
+		l.Status.Sub = &LifecycleStatus{}
+	}
+	return &Lifecycle{Graph: composite.Graph, Initial: composite.Initial, Actions: composite.actions(l.Actions), Status: l.Status.Sub,
+		HistoryLimit: l.HistoryLimit, Now: l.Now}
+}
+
+// exit Leave the current state, after the current sub-state, if any
+func (l *Lifecycle) exit(state LCGState) error {
+	if sub := l.subLifecycle(state); sub != nil {
+		if subState, started, err := sub.GetState(); err == nil && started && !sub.Graph.IsLeafNode(subState) {
+			if err = sub.exit(subState); err != nil {
+				return err
+			}
+		}
+	}
+	if exit, exists := l.Actions.(LCGStateExitAction); exists {
+		return exit.Exit(state)
+	}
+	return nil
+}
+
+// transitionTo Enter in a new state, then in its error child if the action fails, and so on. Return the first error.
+func (l *Lifecycle) transitionTo(from, state LCGState, reason string) (err error) {
+	for routes := 0; routes <= len(l.Graph); routes++ {
+		if transition, exists := l.Actions.(LCGTransitionAction); exists {
+			transition.OnTransition(from, state)
+		}
+		errEnter := l.enter(state, reason)
+		if errEnter == nil {
+			return err
+		}
+		if err == nil {
+			err = errEnter
+		}
+
+		errorChild := l.Graph[state].ErrorChild
+		if errorChild == nil || *errorChild == state {
+			return err
+		}
+		from, state, reason = state, *errorChild, fmt.Sprintf("error entering %s: %s", l.Graph.StateName(state), errEnter)
+	}
+	return err
+}
+
+// enter Persist the new state in the status and call the action
+func (l *Lifecycle) enter(state LCGState, reason string) error {
+	if _, exists := l.Graph[state]; !exists {
+		return fmt.Errorf("the lifecycle state %s is not a state of the graph", state)
+	}
+
+	now := metav1.NewTime(l.now())
+	name := l.Graph.StateName(state)
+	l.Status.History = append(l.Status.History, LifecycleTransition{From: l.Status.State, To: name, At: now, Reason: reason})
+	limit := l.HistoryLimit
+	if limit <= 0 {
+		limit = DefaultLifecycleHistoryLimit
+	}
+	if len(l.Status.History) > limit {
+		l.Status.History = l.Status.History[len(l.Status.History)-limit:]
+	}
+	l.Status.PreviousState = l.Status.State
+	l.Status.State = name
+	l.Status.EnteredAt = now
+	l.Status.Sub = nil
+
+	if l.Actions != nil {
+		if err := l.Actions.Enter(state); err != nil {
+			return err
+		}
+	}
+	if sub := l.subLifecycle(state); sub != nil {
+		_, err := sub.Step(nil) // Enter the initial sub-state
+		return err
+	}
+	return nil
+}
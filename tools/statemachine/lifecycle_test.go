@@ -0,0 +1,175 @@
+// Copyright 2021 Orange SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.package apis
+
+package statemachine
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	Provisioning LCGState = iota
+	Running
+	Upgrading
+	Failed
+	Deleted
+)
+
+var lcGraph = LCGGraph{
+	Provisioning: LCGNodeInfo{Name: "Provisioning", Children: LCGChildren{Failed, Running}},
+	Running:      LCGNodeInfo{Name: "Running", Children: LCGChildren{Deleted, Upgrading}},
+	Upgrading:    LCGNodeInfo{Name: "Upgrading", Children: LCGChildren{Failed, Running}},
+	Failed:       LCGNodeInfo{Name: "Failed", Children: LCGChildren{Deleted, Running}},
+	Deleted:      LCGNodeInfo{Name: "Deleted"},
+}
+
+type lifecycleActions struct {
+	entered []string
+}
+
+func (a *lifecycleActions) Enter(state LCGState) error {
+	a.entered = append(a.entered, lcGraph.StateName(state))
+	return nil
+}
+
+// restart Simulate a new reconciliation, after a restart of the operator: a new lifecycle with the status read from the CR
+func restart(t *testing.T, status *LifecycleStatus, actions LCGStateAction, now *time.Time) *Lifecycle {
+	b, err := json.Marshal(status)
+	require.NoError(t, err)
+	persisted := &LifecycleStatus{}
+	require.NoError(t, json.Unmarshal(b, persisted))
+
+	lifecycle := NewLifecycle(lcGraph, Provisioning, actions, persisted)
+	lifecycle.SetTimeout(Upgrading, 10*time.Minute, Failed)
+	lifecycle.Now = func() time.Time { return *now }
+	return lifecycle
+}
+
+func TestLifecycle(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	actions := &lifecycleActions{}
+
+	// 1st reconciliation: the initial state is entered
+	lifecycle := restart(t, &LifecycleStatus{}, actions, &now)
+	entered, err := lifecycle.Step(LCGEvents{Running})
+	require.NoError(t, err)
+	require.True(t, entered)
+	require.Equal(t, "Provisioning", lifecycle.Status.State)
+	require.Equal(t, "", lifecycle.Status.PreviousState)
+
+	// Next ones: resume, provisioned, then upgrade
+	for _, event := range []LCGState{DefaultState, Upgrading} {
+		now = now.Add(time.Minute)
+		lifecycle = restart(t, lifecycle.Status, actions, &now)
+		entered, err = lifecycle.Step(LCGEvents{event})
+		require.NoError(t, err)
+		require.True(t, entered)
+	}
+	require.Equal(t, "Upgrading", lifecycle.Status.State)
+	require.Equal(t, "Running", lifecycle.Status.PreviousState)
+	require.Equal(t, now, lifecycle.Status.EnteredAt.Time)
+
+	// The upgrade spans several reconciliations
+	now = now.Add(6 * time.Minute)
+	lifecycle = restart(t, lifecycle.Status, actions, &now)
+	entered, err = lifecycle.Step(LCGEvents{})
+	require.NoError(t, err)
+	require.False(t, entered, "Waiting for the upgrade")
+	remaining, exists := lifecycle.TimeoutIn()
+	require.True(t, exists)
+	require.Equal(t, 4*time.Minute, remaining)
+	require.Equal(t, 6*time.Minute, lifecycle.TimeInState())
+
+	// Timeout exceeded
+	now = now.Add(5 * time.Minute)
+	lifecycle = restart(t, lifecycle.Status, actions, &now)
+	entered, err = lifecycle.Step(LCGEvents{})
+	require.NoError(t, err)
+	require.True(t, entered)
+	require.Equal(t, "Failed", lifecycle.Status.State)
+	last := lifecycle.Status.History[len(lifecycle.Status.History)-1]
+	require.Equal(t, LifecycleTransition{From: "Upgrading", To: "Failed", At: lifecycle.Status.EnteredAt, Reason: "timeout of 10m0s exceeded in Upgrading"}, last)
+	_, exists = lifecycle.TimeoutIn()
+	require.False(t, exists, "No timeout in Failed state")
+
+	// Until the end of life
+	lifecycle = restart(t, lifecycle.Status, actions, &now)
+	entered, _ = lifecycle.Step(LCGEvents{Deleted})
+	require.True(t, entered)
+	entered, _ = lifecycle.Step(LCGEvents{DefaultState})
+	require.False(t, entered, "A leaf state is never left")
+
+	require.Equal(t, []string{"Provisioning", "Running", "Upgrading", "Failed", "Deleted"}, actions.entered)
+	require.Len(t, lifecycle.Status.History, 5)
+}
+
+func TestLifecycleHistoryLimit(t *testing.T) {
+	lifecycle := NewLifecycle(lcGraph, Running, nil, &LifecycleStatus{})
+	lifecycle.HistoryLimit = 3
+	lifecycle.Step(nil)
+	for i := 0; i < 4; i++ {
+		lifecycle.Step(LCGEvents{Upgrading})
+		lifecycle.Step(LCGEvents{Running})
+	}
+	require.Len(t, lifecycle.Status.History, 3)
+	require.Equal(t, "Running", lifecycle.Status.History[2].To)
+}
+
+func TestLifecycleUnknownState(t *testing.T) {
+	lifecycle := NewLifecycle(lcGraph, Provisioning, nil, &LifecycleStatus{State: "Removed"})
+	_, err := lifecycle.Step(LCGEvents{DefaultState})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"Removed"`)
+}
+
+type failingLifecycleActions struct {
+	transitions []string
+}
+
+func (a *failingLifecycleActions) Enter(state LCGState) error {
+	if state == Upgrading {
+		return errors.New("no new version")
+	}
+	return nil
+}
+
+func (a *failingLifecycleActions) OnTransition(from, to LCGState) {
+	a.transitions = append(a.transitions, lcGraph.StateName(from)+"->"+lcGraph.StateName(to))
+}
+
+func TestLifecycleErrorChild(t *testing.T) {
+	graph := LCGGraph{}
+	for state, node := range lcGraph {
+		graph[state] = node
+	}
+	upgrading := graph[Upgrading]
+	upgrading.ErrorChild = ErrorChild(Failed)
+	graph[Upgrading] = upgrading
+
+	actions := &failingLifecycleActions{}
+	lifecycle := NewLifecycle(graph, Running, actions, &LifecycleStatus{})
+	lifecycle.Step(nil)
+	entered, err := lifecycle.Step(LCGEvents{Upgrading})
+	require.True(t, entered)
+	require.EqualError(t, err, "no new version")
+	require.Equal(t, "Failed", lifecycle.Status.State)
+	require.Equal(t, "Upgrading", lifecycle.Status.PreviousState)
+	require.Equal(t, "error entering Upgrading: no new version", lifecycle.Status.History[2].Reason)
+	require.Equal(t, []string{"-1->Running", "Running->Upgrading", "Upgrading->Failed"}, actions.transitions)
+}
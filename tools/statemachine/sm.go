@@ -16,6 +16,7 @@ package statemachine
 
 import (
 	"strconv"
+	"time"
 )
 
 /** The StateMachine allows to build a LCG (Life Cycle Graph) to represent the multiple states an aplication
@@ -50,9 +51,24 @@ func (t LCGChildren) IsDefault(state LCGState) bool {
 	return (exists && state == dft)
 }
 
+// LCGGuard is a predicate which allows (or not) a transition triggered by the events
+type LCGGuard func(from, to LCGState) bool
+
 type LCGNodeInfo struct {
 	Name     string
 	Children LCGChildren
+
+	// Guards of the transitions to the children, optional. A triggered child whose guard returns false is skipped.
+	Guards map[LCGState]LCGGuard
+	// ErrorChild is the state to enter when the action entering this state fails, optional (see ErrorChild())
+	ErrorChild *LCGState
+	// Composite is the sub-graph of a composite state, optional (see LCGComposite)
+	Composite *LCGComposite
+}
+
+// ErrorChild Return the error child of a node, for a LCGNodeInfo literal: ErrorChild: oktsm.ErrorChild(Failed)
+func ErrorChild(state LCGState) *LCGState {
+	return &state
 }
 
 type LCGGraph map[LCGState]LCGNodeInfo
@@ -61,6 +77,16 @@ func (g LCGGraph) IsLeafNode(state LCGState) bool {
 	return len(g[state].Children) == 0
 }
 
+// State Return the state named as provided
+func (g LCGGraph) State(name string) (state LCGState, found bool) {
+	for state := range g {
+		if g.StateName(state) == name {
+			return state, true
+		}
+	}
+	return DefaultState, false
+}
+
 func (g LCGGraph) StateName(state LCGState) (name string) {
 	name = g[state].Name
 	if name == "" {
@@ -76,14 +102,29 @@ type LCGStateAction interface {
 	Enter(state LCGState) error
 }
 
+// LCGStateExitAction is an optional interface of the actions: "Exit" is called each time the machine leaves a state.
+// The machine stays in the state if an error is returned.
+type LCGStateExitAction interface {
+	Exit(state LCGState) error
+}
+
+// LCGTransitionAction is an optional interface of the actions: "OnTransition" is called on each transition, after leaving the
+// previous state (DefaultState when the machine is set to a state) and before entering the next one.
+type LCGTransitionAction interface {
+	OnTransition(from, to LCGState)
+}
+
 type Machine struct {
 	Graph           LCGGraph
 	Actions         LCGStateAction
 	curState        LCGState
 	prevState       LCGState
-	pathInGraph     []string
-	pathLoopsCount  uint
+	history         *lcgHistory // The steps of the path in graph, nil when disabled
 	pathLengthLimit uint
+	sub             *Machine // The sub-machine of the current state, if composite
+
+	// Now returns the current time, to date the steps of the path in graph. time.Now() if not set.
+	Now func() time.Time
 }
 
 func (m Machine) IsOFF() bool {
@@ -98,15 +139,22 @@ func (m *Machine) setOFF() {
 
 // SetState Set current state for this mathine
 // Can not set the same state twice
+// The error of the action is dropped, see EnterState()
 func (m *Machine) SetState(state LCGState) (entered bool) {
+	entered, _ = m.EnterState(state)
+	return entered
+}
+
+// EnterState Same as SetState() and return the error of the action entering the state (see EnterNextState())
+func (m *Machine) EnterState(state LCGState) (entered bool, err error) {
 	if m.curState != state || m.IsOFF() {
 		if m.IsPathInGraphEnabled() {
 			m.EnablePathInGraph() // Reset path for a new browsing
 		}
-		m.enterInState(state)
-		return true
+		m.setOFF() // A new browsing: the previous state is not a loop candidate
+		return true, m.transitionTo(DefaultState, state, nil)
 	}
-	return false
+	return false, nil
 }
 
 // GetState Get current state for this machine
@@ -133,77 +181,140 @@ func (e LCGEvents) IsTriggeringState(state LCGState, isDefaultState bool) bool {
 	return false
 }
 
-// enterInState Set the new current state
-// The machine is set to OFF if browsing a leaf node
-func (m *Machine) enterInState(state LCGState) (err error) {
-	var loopPathLength uint
+func (m *Machine) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
 
+// enterInState Set the new current state, entered on the events provided
+// The machine is set to OFF if browsing a leaf node
+func (m *Machine) enterInState(state LCGState, events LCGEvents) (err error) {
 	if m.IsOFF() {
 		// Hack to ensure that a never used machine will appear ON
 		// after entering in state 0 (because all is initialized at 0 at struct creation)
 		m.prevState = DefaultState
 	} else {
-		if m.prevState == state {
-			loopPathLength = 2 // The length of the path in the loop is 2 nodes
-		}
 		m.prevState = m.curState
 	}
 	m.curState = state
 
-	m.addStateToPath(state, loopPathLength)
+	if last := m.history.last(); last != nil && m.sub != nil {
+		last.Sub = m.sub.GetHistory()
+	}
+	m.sub = nil
+	if m.history != nil {
+		m.history.enter(LCGStep{State: state, Name: m.Graph.StateName(state), EnteredAt: m.now(), Events: append(LCGEvents(nil), events...)})
+	}
 
 	if m.Actions != nil {
 		err = m.Actions.Enter(m.curState)
 	}
+	if composite := m.Graph[state].Composite; composite != nil && err == nil {
+		err = m.enterComposite(composite)
+	}
+	if step := m.history.last(); step != nil {
+		step.Err = err
+	}
 
 	// Browsing is at its end (in a leaf node), thus set the machine to OFF
 	if m.Graph.IsLeafNode(state) {
+		m.history.close(m.now())
 		m.setOFF()
 	}
 
 	return err
 }
 
-// EnterNextState Trigger each event up to the first allowing to throw a new state.
-func (m *Machine) EnterNextState(events LCGEvents) (entered bool, err error) {
-	children := m.Graph[m.curState].Children
+// NextState Return the first child of a state, by priority order, triggered by the events (see LCGEvents.IsTriggeringState())
+// and allowed by its guard, if any
+func (g LCGGraph) NextState(state LCGState, events LCGEvents) (next LCGState, found bool) {
+	children := g[state].Children
 	exists, defaultChild := children.Default()
 	if !exists {
-		return false, nil
+		return DefaultState, false
 	}
 
 	for _, nextState := range children {
 		if events.IsTriggeringState(nextState, (nextState == defaultChild)) {
-			// Enter into next state !!
-			err = m.enterInState(nextState)
-			return true, err
+			if guard, exists := g[state].Guards[nextState]; exists && guard != nil && !guard(state, nextState) {
+				continue
+			}
+			return nextState, true
+		}
+	}
+
+	return DefaultState, false
+}
+
+// EnterNextState Trigger each event up to the first allowing to throw a new state.
+// The current state is left (see LCGStateExitAction), then the transition is notified (see LCGTransitionAction) and the new state is entered.
+// When the action entering the new state fails, the error child of this state, if any, is entered in turn (without leaving the state
+// which failed), and so on. The error returned is the one of the first action which failed.
+// In a composite state, the events are first triggered in its sub-machine and bubble to this machine if none throws a new sub-state.
+func (m *Machine) EnterNextState(events LCGEvents) (entered bool, err error) {
+	if m.sub != nil && !m.sub.IsOFF() {
+		if entered, err = m.sub.EnterNextState(events); entered || err != nil {
+			return entered, err
 		}
 	}
 
-	return false, err
+	nextState, found := m.Graph.NextState(m.curState, events)
+	if !found {
+		return false, nil
+	}
+
+	if err = m.exit(); err != nil {
+		return false, err
+	}
+
+	// Enter into next state !!
+	err = m.transitionTo(m.curState, nextState, events)
+	return true, err
 }
 
+// transitionTo Enter in a new state on the events provided. If the action fails, the error child of the state, if any, is entered in turn.
+// Return the error of the first action which failed.
+func (m *Machine) transitionTo(from, state LCGState, events LCGEvents) (err error) {
+	for routes := 0; routes <= len(m.Graph); routes++ {
+		if transition, exists := m.Actions.(LCGTransitionAction); exists {
+			transition.OnTransition(from, state)
+		}
+		errEnter := m.enterInState(state, events)
+		if errEnter == nil {
+			return err
+		}
+		if err == nil {
+			err = errEnter
+		}
+
+		errorChild := m.Graph[state].ErrorChild
+		if errorChild == nil || *errorChild == state {
+			return err
+		}
+		from, state, events = state, *errorChild, nil
+	}
+	return err
+}
+
+// EnablePathInGraph Record the steps of the path in graph (see GetHistory()), or reset them
 func (m *Machine) EnablePathInGraph() {
-	/*
-		m.pathInGraph = make([]string, 0)
-		m.appendToPath(">") // Initialize with the first element
-	*/
-	m.pathInGraph = []string{">"}
 	if m.pathLengthLimit == 0 {
 		m.pathLengthLimit = 512
 	}
+	m.history = newLCGHistory(m.pathLengthLimit)
 }
 
 func (m *Machine) DisablePathInGraph() {
-	m.pathInGraph = nil
+	m.history = nil
 }
 
 func (m *Machine) IsPathInGraphEnabled() bool {
-	return m.pathInGraph != nil
+	return m.history != nil
 }
 
-// SetPathLengthLimit Defines the size of the slice storing the path in graph, i.e. the maximum states to store. Nnote that loops in graph count for 2 states.
-// This slice store also the separator (">") in addtion to the states.
+// SetPathLengthLimit Defines the maximum steps to record in the path in graph, the oldest are dropped. Nnote that loops in graph count for 2 states.
 // If not set, the max is by default limited to 512. You can define more if needed.
 // Min is 5 and Maximum is 1024
 func (m *Machine) SetPathLengthLimit(max uint) {
@@ -213,61 +324,34 @@ func (m *Machine) SetPathLengthLimit(max uint) {
 		max = 1024
 	}
 	m.pathLengthLimit = max
+	if m.history != nil {
+		m.history = m.history.resize(max)
+	}
 }
 
+// GetPathInGraph Render the path in graph as a string where the loops between 2 states are compacted, i.e. ">Start>(Run<<>>Servicing)x3>Stopping>End"
 func (m *Machine) GetPathInGraph() (path string) {
-	path = ""
-	for _, p := range m.pathInGraph {
-		path += p
+	if m.history == nil {
+		return ""
 	}
-	return path
+	return renderPath(m.Graph, m.GetHistory())
 }
 
-func (m *Machine) appendToPath(elem ...string) {
-	m.pathInGraph = append(m.pathInGraph, elem...)
-
-	if len(m.pathInGraph) > int(m.pathLengthLimit) {
-		m.pathInGraph = m.pathInGraph[2:]
+// GetPathStates Return the states of the path in graph, without loop compaction (up to the path length limit)
+func (m *Machine) GetPathStates() []LCGState {
+	steps := m.history.Steps()
+	states := make([]LCGState, 0, len(steps))
+	for _, step := range steps {
+		states = append(states, step.State)
 	}
+	return states
 }
 
-/*
-func (m *Machine) compactAndAppendToPath(elem ...string) {
-	newLength := len(m.pathInGraph) - int(m.pathCompactionNeeded) - int(m.pathCompactionNeeded/2)
-	m.pathInGraph = m.pathInGraph[:newLength]
-	m.pathInGraph = append(m.pathInGraph, elem...)
-}
-*/
-
-// TODO manage maximum path length to limit its size ?
-func (m *Machine) addStateToPath(state LCGState, loopPathLength uint) {
-	if m.pathInGraph == nil {
-		return
+// GetHistory Return the steps of the path in graph, from the oldest (up to the path length limit), with their timing, events and error
+func (m *Machine) GetHistory() []LCGStep {
+	steps := m.history.Steps()
+	if len(steps) > 0 && m.sub != nil {
+		steps[len(steps)-1].Sub = m.sub.GetHistory()
 	}
-
-	stateName := m.Graph.StateName(state)
-
-	if m.Graph.IsLeafNode(state) {
-		m.appendToPath(stateName)
-		m.pathLoopsCount = 0
-	} else {
-		if loopPathLength == 0 { // There's no loop
-			m.pathLoopsCount = 0
-			m.appendToPath(stateName, ">")
-			return
-		}
-		m.pathLoopsCount++
-
-		pathLen := len(m.pathInGraph)
-		m.pathInGraph[pathLen-int(loopPathLength*2+1)] = ">("
-		m.pathInGraph[pathLen-3] = "<<>>"
-		count := float32(m.pathLoopsCount)/2.0 + 1.0
-		//loops := fmt.Sprintf("%.1f", count)
-		if float32(int(count)) == count {
-			m.pathInGraph[pathLen-1] = ")x" + strconv.Itoa(int(count)) + ">"
-		} else {
-			m.pathInGraph[pathLen-1] = ")x" + strconv.Itoa(int(count)) + ">" + stateName + ">"
-		}
-	}
-
+	return steps
 }
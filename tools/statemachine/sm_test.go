@@ -17,6 +17,7 @@ package statemachine
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -181,3 +182,128 @@ func TestStateMachine(t *testing.T) {
 	path = sm.GetPathInGraph()
 	fmt.Println(path)
 }
+
+// tracedDatabase traces the actions and transitions, fails to enter or leave the states listed
+type tracedDatabase struct {
+	trace      []string
+	failEnter  map[LCGState]bool
+	failExit   map[LCGState]bool
+	graph      LCGGraph
+	allowStops bool
+}
+
+func (db *tracedDatabase) Enter(state LCGState) error {
+	db.trace = append(db.trace, "enter "+db.graph.StateName(state))
+	if db.failEnter[state] {
+		return fmt.Errorf("can not enter %s", db.graph.StateName(state))
+	}
+	return nil
+}
+
+func (db *tracedDatabase) Exit(state LCGState) error {
+	db.trace = append(db.trace, "exit "+db.graph.StateName(state))
+	if db.failExit[state] {
+		return fmt.Errorf("can not exit %s", db.graph.StateName(state))
+	}
+	return nil
+}
+
+func (db *tracedDatabase) OnTransition(from, to LCGState) {
+	db.trace = append(db.trace, db.graph.StateName(from)+"->"+db.graph.StateName(to))
+}
+
+func TestStateMachineGuardsAndCallbacks(t *testing.T) {
+	db := &tracedDatabase{failEnter: map[LCGState]bool{}, failExit: map[LCGState]bool{}}
+	graph := LCGGraph{
+		Start: LCGNodeInfo{Name: "Start", Children: LCGChildren{End, Run}, ErrorChild: ErrorChild(Stop)},
+		Run: LCGNodeInfo{Name: "Run", Children: LCGChildren{Service, Stop},
+			Guards: map[LCGState]LCGGuard{Stop: func(from, to LCGState) bool { return db.allowStops }}},
+		Service: LCGNodeInfo{Name: "Servicing", Children: LCGChildren{Stop, Run}, ErrorChild: ErrorChild(Stop)},
+		Stop:    LCGNodeInfo{Name: "Stopping", Children: LCGChildren{End}},
+		End:     LCGNodeInfo{Name: "End"},
+	}
+	db.graph = graph
+	sm := &Machine{Graph: graph, Actions: db}
+	sm.EnablePathInGraph()
+
+	entered, err := sm.EnterState(Start)
+	require.True(t, entered)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-1->Start", "enter Start"}, db.trace)
+
+	// Guards
+	entered, _ = sm.EnterNextState(LCGEvents{DefaultState})
+	require.True(t, entered)
+	require.Equal(t, Run, sm.GetState())
+	entered, _ = sm.EnterNextState(LCGEvents{Stop})
+	require.False(t, entered, "The transition to Stop is not allowed by its guard")
+	require.Equal(t, Run, sm.GetState())
+
+	// A failing exit keeps the current state
+	db.failExit[Run] = true
+	entered, err = sm.EnterNextState(LCGEvents{Service})
+	require.False(t, entered)
+	require.EqualError(t, err, "can not exit Run")
+	require.Equal(t, Run, sm.GetState())
+	db.failExit[Run] = false
+
+	// A failing entry routes to the error child
+	db.trace = nil
+	db.failEnter[Service] = true
+	entered, err = sm.EnterNextState(LCGEvents{Service})
+	require.True(t, entered)
+	require.EqualError(t, err, "can not enter Servicing", "The first error is returned")
+	require.Equal(t, Stop, sm.GetState())
+	require.Equal(t, []string{"exit Run", "Run->Servicing", "enter Servicing", "Servicing->Stopping", "enter Stopping"}, db.trace)
+	require.Equal(t, ">Start>Run>Servicing>Stopping>", sm.GetPathInGraph())
+
+	// The guard allows now the Stop transition
+	db.allowStops = true
+	sm.SetState(Run)
+	entered, _ = sm.EnterNextState(LCGEvents{Stop})
+	require.True(t, entered)
+	require.Equal(t, Stop, sm.GetState())
+}
+
+func TestStateMachineHistory(t *testing.T) {
+	db := &tracedDatabase{failEnter: map[LCGState]bool{Service: true}, graph: tGraph}
+	graph := LCGGraph{}
+	for state, node := range tGraph {
+		graph[state] = node
+	}
+	graph[Service] = LCGNodeInfo{Name: "Servicing", Children: LCGChildren{Stop, Run}, ErrorChild: ErrorChild(Run)}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	sm := &Machine{Graph: graph, Actions: db, Now: func() time.Time { now = now.Add(time.Second); return now }}
+	require.Empty(t, sm.GetHistory(), "Disabled by default")
+	sm.EnablePathInGraph()
+
+	sm.SetState(Start)
+	sm.EnterNextState(LCGEvents{DefaultState})
+	_, err := sm.EnterNextState(LCGEvents{Service})
+	require.EqualError(t, err, "can not enter Servicing")
+	sm.EnterNextState(LCGEvents{Stop})
+	sm.EnterNextState(LCGEvents{DefaultState})
+
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, []LCGStep{
+		{State: Start, Name: "Start", EnteredAt: start.Add(1 * time.Second), Duration: time.Second},
+		{State: Run, Name: "Run", EnteredAt: start.Add(2 * time.Second), Duration: time.Second, Events: LCGEvents{DefaultState}},
+		{State: Service, Name: "Servicing", EnteredAt: start.Add(3 * time.Second), Duration: time.Second, Events: LCGEvents{Service}, Err: err},
+		{State: Run, Name: "Run", EnteredAt: start.Add(4 * time.Second), Duration: time.Second},
+		{State: Stop, Name: "Stopping", EnteredAt: start.Add(5 * time.Second), Duration: time.Second, Events: LCGEvents{Stop}},
+		{State: End, Name: "End", EnteredAt: start.Add(6 * time.Second), Duration: time.Second, Events: LCGEvents{DefaultState}},
+	}, sm.GetHistory())
+	require.Equal(t, ">Start>(Run<<>>Servicing)x1>Run>Stopping>End", sm.GetPathInGraph())
+
+	// Bounded: the oldest steps are dropped
+	sm.SetPathLengthLimit(5)
+	require.Equal(t, []LCGState{Run, Service, Run, Stop, End}, sm.GetPathStates())
+	db.failEnter[Service] = false
+	sm.SetState(Run)
+	for i := 0; i < 10; i++ {
+		sm.EnterNextState(LCGEvents{Service, Run})
+	}
+	require.Equal(t, []LCGState{Run, Service, Run, Service, Run}, sm.GetPathStates())
+	require.Equal(t, ">(Run<<>>Servicing)x2>Run>", sm.GetPathInGraph())
+	require.Zero(t, sm.GetHistory()[4].Duration, "Still in the state")
+}